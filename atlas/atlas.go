@@ -0,0 +1,75 @@
+// Package atlas writes a tile set in the formats
+// used by game engines and front-end libraries.
+package atlas
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lucasepe/tiles/tileset"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported formats.
+const (
+	// FormatYAML is the tiles YAML tileset.
+	FormatYAML = "yaml"
+	// FormatJSON is the TexturePacker JSON Hash atlas (Phaser, PixiJS).
+	FormatJSON = "json"
+	// FormatJSONArray is the TexturePacker JSON Array atlas.
+	FormatJSONArray = "json-array"
+	// FormatLibGDX is the libGDX TextureAtlas text file.
+	FormatLibGDX = "libgdx"
+	// FormatGodot is the Godot 4 TileSet resource (.tres).
+	FormatGodot = "godot"
+)
+
+// CheckFormat returns an error if the format is not supported.
+func CheckFormat(format string) error {
+	switch strings.ToLower(format) {
+	case FormatYAML, FormatJSON, FormatJSONArray, FormatLibGDX, FormatGodot:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported atlas format %q (yaml, json, json-array, libgdx or godot)", format)
+}
+
+// NeedsImageFile returns true if the format references the
+// atlas image as an external file (only YAML can embed it).
+func NeedsImageFile(format string) bool {
+	return !strings.EqualFold(format, FormatYAML)
+}
+
+// Encode writes the tile set in the specified format; all the
+// formats but YAML need the external image file (ImageFile).
+func Encode(wr io.Writer, ts *tileset.Tileset, format string) error {
+	format = strings.ToLower(format)
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+
+	if NeedsImageFile(format) && ts.ImageFile == "" {
+		return fmt.Errorf("the %s format needs an external atlas image file", format)
+	}
+
+	var dat []byte
+	var err error
+
+	switch format {
+	case FormatYAML:
+		dat, err = yaml.Marshal(ts)
+	case FormatJSON, FormatJSONArray:
+		dat, err = ts.MarshalTexturePacker(format == FormatJSONArray)
+	case FormatLibGDX:
+		return LibGDX(wr, ts)
+	case FormatGodot:
+		return Godot(wr, ts)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.Write(dat)
+	return err
+}
@@ -0,0 +1,99 @@
+package atlas
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lucasepe/tiles/tileset"
+)
+
+func sampleTileset() *tileset.Tileset {
+	return &tileset.Tileset{
+		ImageFile: "sheet.png",
+		Width:     96, Height: 64,
+		Tiles: []*tileset.Tile{
+			{ID: "grass", MinX: 0, MinY: 0, MaxX: 32, MaxY: 32},
+			{ID: "water", MinX: 32, MinY: 0, MaxX: 64, MaxY: 32},
+			{ID: "house", MinX: 0, MinY: 32, MaxX: 64, MaxY: 64},
+		},
+	}
+}
+
+func TestLibGDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := LibGDX(&buf, sampleTileset()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"\nsheet.png\nsize: 96,64\n",
+		"water\n  rotate: false\n  xy: 32, 0\n  size: 32, 32\n",
+		"house\n  rotate: false\n  xy: 0, 32\n  size: 64, 32\n  orig: 64, 32\n",
+	}
+	for _, el := range want {
+		if !strings.Contains(buf.String(), el) {
+			t.Errorf("got [%v] want [%v]", buf.String(), el)
+		}
+	}
+}
+
+func TestGodot(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Godot(&buf, sampleTileset()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`[ext_resource type="Texture2D" path="sheet.png" id="1"]`,
+		"texture_region_size = Vector2i(32, 32)",
+		`1:0/0/custom_data_0 = "water"`,
+		"0:1/size_in_atlas = Vector2i(2, 1)",
+		`0:1/0/custom_data_0 = "house"`,
+		"tile_size = Vector2i(32, 32)",
+	}
+	for _, el := range want {
+		if !strings.Contains(buf.String(), el) {
+			t.Errorf("got [%v] want [%v]", buf.String(), el)
+		}
+	}
+
+	if strings.Contains(buf.String(), "1:0/size_in_atlas") {
+		t.Errorf("single cell tiles must not have size_in_atlas")
+	}
+
+	// a 48px tile does not span whole cells of 32px
+	ts := sampleTileset()
+	ts.Tiles = append(ts.Tiles, &tileset.Tile{ID: "tree", MinX: 64, MinY: 0, MaxX: 96, MaxY: 48})
+	if err := Godot(&bytes.Buffer{}, ts); err == nil {
+		t.Errorf("expected an error for a tile off the grid")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	embedded := sampleTileset()
+	embedded.ImageFile = ""
+
+	tests := []struct {
+		ts     *tileset.Tileset
+		format string
+		ok     bool
+	}{
+		{sampleTileset(), FormatYAML, true},
+		{sampleTileset(), FormatJSON, true},
+		{sampleTileset(), "LibGDX", true},
+		{sampleTileset(), FormatGodot, true},
+		{sampleTileset(), "tmx", false},
+		{embedded, FormatYAML, true},
+		{embedded, FormatGodot, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			err := Encode(&bytes.Buffer{}, tt.ts, tt.format)
+			if got := err == nil; got != tt.ok {
+				t.Errorf("got [%v] want [%v] (%v)", got, tt.ok, err)
+			}
+		})
+	}
+}
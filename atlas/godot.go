@@ -0,0 +1,135 @@
+package atlas
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/lucasepe/tiles/tileset"
+)
+
+// Godot writes the tile set as a Godot 4 TileSet resource (.tres)
+// with a single atlas source. Godot addresses the tiles on a grid:
+// the grid cell is the size of the smallest tile, the margins and
+// the separation are taken from the tile positions; the other tiles
+// must be on the grid and span whole cells (i.e. a sprite sheet or
+// tiles composed with sizes multiple of the smallest one). The tile
+// ids are stored in the 'id' custom data layer; the texture path is
+// relative to the resource.
+func Godot(wr io.Writer, ts *tileset.Tileset) error {
+	if len(ts.Tiles) == 0 {
+		return fmt.Errorf("tileset has no tiles")
+	}
+
+	xs, ys := make([]span, len(ts.Tiles)), make([]span, len(ts.Tiles))
+	for i, el := range ts.Tiles {
+		xs[i] = span{el.MinX, el.MaxX}
+		ys[i] = span{el.MinY, el.MaxY}
+	}
+
+	gx, okx := gridOf(xs)
+	gy, oky := gridOf(ys)
+	if gx.cell <= 0 || gy.cell <= 0 {
+		return fmt.Errorf("tileset has empty tiles")
+	}
+	for i, el := range ts.Tiles {
+		if !okx[i] || !oky[i] {
+			return fmt.Errorf("tile %q (%d, %d, %dx%d) is not on the %dx%d grid of the smallest tile: Godot atlases need a grid",
+				el.ID, el.MinX, el.MinY, el.MaxX-el.MinX, el.MaxY-el.MinY, gx.cell, gy.cell)
+		}
+	}
+
+	bw := bufio.NewWriter(wr)
+
+	fmt.Fprintln(bw, `[gd_resource type="TileSet" load_steps=3 format=3]`)
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "[ext_resource type=\"Texture2D\" path=%s id=\"1\"]\n", strconv.Quote(ts.ImageFile))
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, `[sub_resource type="TileSetAtlasSource" id="TileSetAtlasSource_1"]`)
+	fmt.Fprintln(bw, `texture = ExtResource("1")`)
+	if gx.margin > 0 || gy.margin > 0 {
+		fmt.Fprintf(bw, "margins = Vector2i(%d, %d)\n", gx.margin, gy.margin)
+	}
+	if gx.separation > 0 || gy.separation > 0 {
+		fmt.Fprintf(bw, "separation = Vector2i(%d, %d)\n", gx.separation, gy.separation)
+	}
+	fmt.Fprintf(bw, "texture_region_size = Vector2i(%d, %d)\n", gx.cell, gy.cell)
+
+	for _, el := range ts.Tiles {
+		col, cols := gx.cells(span{el.MinX, el.MaxX})
+		row, rows := gy.cells(span{el.MinY, el.MaxY})
+
+		key := fmt.Sprintf("%d:%d", col, row)
+		fmt.Fprintf(bw, "%s/0 = 0\n", key)
+		if cols > 1 || rows > 1 {
+			fmt.Fprintf(bw, "%s/size_in_atlas = Vector2i(%d, %d)\n", key, cols, rows)
+		}
+		fmt.Fprintf(bw, "%s/0/custom_data_0 = %s\n", key, strconv.Quote(el.ID))
+	}
+
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "[resource]")
+	fmt.Fprintf(bw, "tile_size = Vector2i(%d, %d)\n", gx.cell, gy.cell)
+	fmt.Fprintln(bw, `custom_data_layer_0/name = "id"`)
+	fmt.Fprintln(bw, "custom_data_layer_0/type = 4")
+	fmt.Fprintln(bw, `sources/0 = SubResource("TileSetAtlasSource_1")`)
+
+	return bw.Flush()
+}
+
+// span is the extent of a tile along an axis.
+type span struct {
+	min, max int
+}
+
+// axisGrid is the grid of an atlas along an axis.
+type axisGrid struct {
+	margin, separation, cell int
+}
+
+// cells returns the first cell of the span and the number of cells.
+func (g axisGrid) cells(s span) (first, count int) {
+	step := g.cell + g.separation
+	return (s.min - g.margin) / step, (s.max - s.min + g.separation) / step
+}
+
+// gridOf returns the grid of the spans: the cell is the smallest span,
+// the margin the smallest start and the separation the smallest gap
+// between the starts; ok tells for each span if it is on the grid.
+func gridOf(spans []span) (g axisGrid, ok []bool) {
+	g.cell, g.margin = spans[0].max-spans[0].min, spans[0].min
+	for _, el := range spans[1:] {
+		g.cell = minInt(g.cell, el.max-el.min)
+		g.margin = minInt(g.margin, el.min)
+	}
+
+	gap := -1
+	for _, el := range spans {
+		if d := el.min - g.margin - g.cell; d >= 0 && (gap < 0 || d < gap) {
+			gap = d
+		}
+	}
+	if gap > 0 {
+		g.separation = gap
+	}
+
+	ok = make([]bool, len(spans))
+	if g.cell <= 0 {
+		return g, ok
+	}
+
+	step := g.cell + g.separation
+	for i, el := range spans {
+		ok[i] = (el.min-g.margin)%step == 0 && (el.max-el.min+g.separation)%step == 0
+	}
+
+	return g, ok
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,37 @@
+package atlas
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/lucasepe/tiles/tileset"
+)
+
+// LibGDX writes the tile set as a libGDX TextureAtlas (the text
+// format read by TextureAtlas and AssetManager) with a single page;
+// the regions are named by the tile ids.
+func LibGDX(wr io.Writer, ts *tileset.Tileset) error {
+	bw := bufio.NewWriter(wr)
+
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, ts.ImageFile)
+	fmt.Fprintf(bw, "size: %d,%d\n", ts.Width, ts.Height)
+	fmt.Fprintln(bw, "format: RGBA8888")
+	fmt.Fprintln(bw, "filter: Nearest,Nearest")
+	fmt.Fprintln(bw, "repeat: none")
+
+	for _, el := range ts.Tiles {
+		w, h := el.MaxX-el.MinX, el.MaxY-el.MinY
+
+		fmt.Fprintln(bw, el.ID)
+		fmt.Fprintln(bw, "  rotate: false")
+		fmt.Fprintf(bw, "  xy: %d, %d\n", el.MinX, el.MinY)
+		fmt.Fprintf(bw, "  size: %d, %d\n", w, h)
+		fmt.Fprintf(bw, "  orig: %d, %d\n", w, h)
+		fmt.Fprintln(bw, "  offset: 0, 0")
+		fmt.Fprintln(bw, "  index: -1")
+	}
+
+	return bw.Flush()
+}
@@ -1,9 +1,9 @@
 package cmd
 
 import (
-	"os"
 	"strings"
 
+	"github.com/lucasepe/tiles/atlas"
 	"github.com/lucasepe/tiles/composer"
 	"github.com/lucasepe/tiles/imagelist"
 	"github.com/spf13/cobra"
@@ -23,15 +23,71 @@ var composeCmd = &cobra.Command{
 			return err
 		}
 
-		return composer.Do(images, os.Stdout)
+		imageOut, err := cmd.Flags().GetString(optImageOut)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(optOutput)
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		css, err := cmd.Flags().GetString(optCSS)
+		if err != nil {
+			return err
+		}
+
+		html, err := cmd.Flags().GetString(optHTML)
+		if err != nil {
+			return err
+		}
+
+		svg, err := cmd.Flags().GetString(optSVG)
+		if err != nil {
+			return err
+		}
+
+		retina, err := cmd.Flags().GetBool(optRetina)
+		if err != nil {
+			return err
+		}
+
+		fp, err := createOutput(output)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+
+		return composer.Do(images, fp,
+			composer.ImageOut(imageOut), composer.Output(output), composer.Format(format),
+			composer.CSS(css), composer.HTML(html), composer.SVG(svg), composer.Retina(retina))
 	},
 }
 
 func init() {
+	composeCmd.Flags().String(optFormat, atlas.FormatYAML, "the tileset format (yaml, json, json-array, libgdx or godot)")
+	composeCmd.Flags().String(optImageOut, "", "write the atlas to this PNG file instead of embedding it")
+	composeCmd.Flags().StringP(optOutput, "o", "", "write the tileset to this file (the image file path is relative to it)")
+	composeCmd.Flags().String(optCSS, "", "write a CSS sprites stylesheet (one class per tile) to this file")
+	composeCmd.Flags().String(optHTML, "", "write an HTML page previewing the CSS sprites to this file")
+	composeCmd.Flags().String(optSVG, "", "write an SVG sprite (one symbol per tile) to this file")
+	composeCmd.Flags().Bool(optRetina, false, "the images are high density (2x): show them at half size in the stylesheet")
+
 	rootCmd.AddCommand(composeCmd)
 }
 
 func composeCmdExample() string {
-	tpl := `  {{APP}} compose /path/to/png/images/ > my_tileset.yml`
+	tpl := `  {{APP}} compose /path/to/png/images/ > my_tileset.yml
+  {{APP}} compose --image-out my_tileset.png /path/to/png/images/ > my_tileset.yml
+  {{APP}} compose --image-out out/atlas.png -o out/atlas.yml /path/to/png/images/
+  {{APP}} compose --format json --image-out atlas.png /path/to/png/images/ > atlas.json
+  {{APP}} compose --format libgdx --image-out atlas.png /path/to/png/images/ > atlas.atlas
+  {{APP}} compose --image-out icons.png --css icons.css --html icons.html /path/to/png/images/ > icons.yml`
 	return strings.Replace(tpl, "{{APP}}", appName(), -1)
 }
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasepe/tiles/atlas"
+	"github.com/lucasepe/tiles/tileset"
+	"github.com/spf13/cobra"
+)
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+	Use:                   "convert <tileset PATH or URL>",
+	Example:               convertCmdExample(),
+	Short:                 "Converts the specified tileset to another atlas format",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		imageOut, err := cmd.Flags().GetString(optImageOut)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(optOutput)
+		if err != nil {
+			return err
+		}
+
+		list, err := tileset.Load(args[0])
+		if err != nil {
+			return err
+		}
+		ts := list[0]
+
+		if imageOut != "" {
+			img, err := ts.Atlas()
+			if err != nil {
+				return err
+			}
+
+			fp, err := os.Create(imageOut)
+			if err != nil {
+				return err
+			}
+			defer fp.Close()
+
+			enc := png.Encoder{CompressionLevel: png.BestCompression}
+			if err := enc.Encode(fp, img); err != nil {
+				return err
+			}
+
+			// the path is relative to the output file (or
+			// to the current directory, if printed)
+			ts.ImageFile = filepath.ToSlash(imageOut)
+			if output != "" {
+				if rel, err := filepath.Rel(filepath.Dir(output), imageOut); err == nil {
+					ts.ImageFile = filepath.ToSlash(rel)
+				}
+			}
+			ts.Data = ""
+		}
+
+		out, err := createOutput(output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		return atlas.Encode(out, ts, format)
+	},
+}
+
+func init() {
+	convertCmd.Flags().String(optFormat, atlas.FormatYAML, "the atlas format (yaml, json, json-array, libgdx or godot)")
+	convertCmd.Flags().String(optImageOut, "", "write the atlas image to this PNG file instead of embedding it")
+	convertCmd.Flags().StringP(optOutput, "o", "", "write the atlas to this file (the image file path is relative to it)")
+
+	rootCmd.AddCommand(convertCmd)
+}
+
+func convertCmdExample() string {
+	tpl := `  {{APP}} convert --format godot --image-out aws.png ../examples/aws_tileset.yml > aws.tres
+  {{APP}} convert --format libgdx atlas.json > atlas.atlas`
+	return strings.Replace(tpl, "{{APP}}", appName(), -1)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lucasepe/tiles/diagram"
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tilemap"
+	"github.com/spf13/cobra"
+)
+
+// diagramCmd represents the diagram command
+var diagramCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MinimumNArgs(1),
+	Use:                   "diagram <diagram URL or PATH>",
+	Short:                 "Lay out and render a diagram of nodes and edges",
+	Example:               diagramCmdExample(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		debug, err := cmd.Flags().GetBool(optDebug)
+		if err != nil {
+			return err
+		}
+
+		emit, err := cmd.Flags().GetBool(optTilemap)
+		if err != nil {
+			return err
+		}
+
+		dg, err := diagram.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		if emit {
+			dat, err := dg.TileMap()
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(dat)
+			return err
+		}
+
+		return dg.Render(os.Stdout, tilemap.Format(format), tilemap.Debug(debug))
+	},
+}
+
+func init() {
+	diagramCmd.Flags().String(optFormat, grid.FormatPNG, "the output format (png or svg)")
+	diagramCmd.Flags().Bool(optDebug, false, "draw the cell coordinates overlay")
+	diagramCmd.Flags().Bool(optTilemap, false, "print the equivalent tilemap (YAML) instead of rendering it")
+
+	rootCmd.AddCommand(diagramCmd)
+}
+
+func diagramCmdExample() string {
+	tpl := `  {{APP}} diagram /path/to/my_diagram.yml > my_diagram.png
+  {{APP}} diagram --format svg /path/to/my_diagram.yml > my_diagram.svg
+  {{APP}} diagram --tilemap /path/to/my_diagram.yml > my_map.yml`
+
+	return strings.Replace(tpl, "{{APP}}", appName(), -1)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasepe/tiles/tilemap"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MinimumNArgs(1),
+	Use:                   "export <tilemap URL or PATH>",
+	Short:                 "Convert a tilemap (and its tilesets) to another format",
+	Example:               exportCmdExample(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		dir, err := cmd.Flags().GetString(optOutputDir)
+		if err != nil {
+			return err
+		}
+
+		tm, err := tilemap.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+
+		switch strings.ToLower(format) {
+		case "tmx":
+			return tm.ExportTMX(dir, name)
+		default:
+			return fmt.Errorf("unsupported export format: %s", format)
+		}
+	},
+}
+
+func init() {
+	exportCmd.Flags().String(optFormat, "tmx", "the export format (tmx)")
+	exportCmd.Flags().StringP(optOutputDir, "o", ".", "the folder where to write the exported files")
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func exportCmdExample() string {
+	tpl := `  {{APP}} export --format tmx /path/to/my_map.yml
+  {{APP}} export --format tmx -o /path/to/tiled/ /path/to/my_map.yml`
+
+	return strings.Replace(tpl, "{{APP}}", appName(), -1)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/lucasepe/tiles/diagram"
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tilemap"
+	"github.com/spf13/cobra"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	Use:                   "import <FORMAT>",
+	Short:                 "Convert a graph from another format to a tilemap",
+}
+
+// importDotCmd represents the import dot command
+var importDotCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.MinimumNArgs(1),
+	Use:                   "dot <Graphviz DOT URL or PATH>",
+	Short:                 "Convert a Graphviz DOT graph to a tilemap",
+	Example:               importDotCmdExample(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atlas, err := cmd.Flags().GetStringSlice(optAtlas)
+		if err != nil {
+			return err
+		}
+
+		size, err := cmd.Flags().GetInt(optTileSize)
+		if err != nil {
+			return err
+		}
+
+		attr, err := cmd.Flags().GetString(optTileAttr)
+		if err != nil {
+			return err
+		}
+
+		render, err := cmd.Flags().GetBool(optRender)
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		dg, err := diagram.LoadDOT(args[0],
+			diagram.AtlasList(atlas...),
+			diagram.TileSize(size),
+			diagram.TileAttr(attr))
+		if err != nil {
+			return err
+		}
+
+		if render {
+			return dg.Render(os.Stdout, tilemap.Format(format))
+		}
+
+		dat, err := dg.TileMap()
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(dat)
+		return err
+	},
+}
+
+func init() {
+	importDotCmd.Flags().StringSlice(optAtlas, []string{}, "the tilesets used by the tilemap (repeatable)")
+	importDotCmd.Flags().Int(optTileSize, 64, "the tile size")
+	importDotCmd.Flags().String(optTileAttr, "tile", "the node attribute holding the tile identifier")
+	importDotCmd.Flags().Bool(optRender, false, "render the tilemap instead of printing it")
+	importDotCmd.Flags().String(optFormat, grid.FormatPNG, "the output format when rendering (png or svg)")
+
+	importCmd.AddCommand(importDotCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func importDotCmdExample() string {
+	tpl := `  {{APP}} import dot --atlas ./examples/aws_tileset.yml /path/to/graph.dot > my_map.yml
+  {{APP}} import dot --atlas ./examples/aws_tileset.yml --render /path/to/graph.dot > graph.png
+  {{APP}} import dot --atlas ./aws_tileset.yml --tile-attr image --tile-size 48 /path/to/graph.dot`
+
+	return strings.Replace(tpl, "{{APP}}", appName(), -1)
+}
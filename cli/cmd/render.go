@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/lucasepe/tiles/grid"
 	"github.com/lucasepe/tiles/tilemap"
 	"github.com/spf13/cobra"
 )
@@ -13,27 +14,43 @@ var renderCmd = &cobra.Command{
 	DisableSuggestions:    true,
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.MinimumNArgs(1),
-	Use:                   "render <tilemap URL or PATH>",
+	Use:                   "render <tilemap (.yml or .tmx) URL or PATH>",
 	Short:                 "Render a square static tilemap",
 	Example:               renderCmdExample(),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		debug, err := cmd.Flags().GetBool(optDebug)
+		if err != nil {
+			return err
+		}
+
 		tm, err := tilemap.Load(args[0])
 		if err != nil {
 			return err
 		}
 
-		return tm.Render(os.Stdout)
+		return tm.Render(os.Stdout, tilemap.Format(format), tilemap.Debug(debug))
 	},
 }
 
 func init() {
+	renderCmd.Flags().String(optFormat, grid.FormatPNG, "the output format (png or svg)")
+	renderCmd.Flags().Bool(optDebug, false, "draw the cell coordinates overlay")
+
 	rootCmd.AddCommand(renderCmd)
 }
 
 func renderCmdExample() string {
 	tpl := `  {{APP}} render https://github.com/lucasepe/tiles/examples/ark.yml
   {{APP}} render /path/to/my_map.yml
-  {{APP}} render /path/to/my_map.yml | viu -`
+  {{APP}} render /path/to/my_map.yml | viu -
+  {{APP}} render --format svg /path/to/my_map.yml > my_map.svg
+  {{APP}} render /path/to/my_tiled_map.tmx > my_map.png
+  {{APP}} render --debug /path/to/my_map.yml > my_map_debug.png`
 
 	return strings.Replace(tpl, "{{APP}}", appName(), -1)
 }
@@ -20,7 +20,26 @@ const (
 
 	appSummary = "Create and inspect a tile set from multiple PNG images."
 
-	optID = "id"
+	optID        = "id"
+	optFormat    = "format"
+	optOutputDir = "output-dir"
+	optDebug     = "debug"
+	optTilemap   = "tilemap"
+	optAtlas     = "atlas"
+	optTileSize  = "tile-size"
+	optTileAttr  = "tile-attr"
+	optRender    = "render"
+	optImageOut  = "image-out"
+	optOutput    = "output"
+	optCSS       = "css"
+	optHTML      = "html"
+	optSVG       = "svg"
+	optRetina    = "retina"
+	optTile      = "tile"
+	optSpacing   = "spacing"
+	optMargin    = "margin"
+	optName      = "name"
+	optIDs       = "ids"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -53,3 +72,12 @@ func init() {
 func appName() string {
 	return filepath.Base(os.Args[0])
 }
+
+// createOutput creates the specified file or,
+// if the name is empty, returns the standard output.
+func createOutput(filename string) (*os.File, error) {
+	if filename == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(filename)
+}
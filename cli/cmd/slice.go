@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/atlas"
+	"github.com/lucasepe/tiles/composer"
+	"github.com/spf13/cobra"
+)
+
+// sliceCmd represents the slice command
+var sliceCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+	Use:                   "slice <SPRITE_SHEET_IMAGE>",
+	Short:                 "Generate a tileset cutting a sprite sheet into tiles of fixed size",
+	Example:               sliceCmdExample(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		size, err := cmd.Flags().GetString(optTile)
+		if err != nil {
+			return err
+		}
+
+		tw, th, err := parseTileSize(size)
+		if err != nil {
+			return err
+		}
+
+		spacing, err := cmd.Flags().GetInt(optSpacing)
+		if err != nil {
+			return err
+		}
+
+		margin, err := cmd.Flags().GetInt(optMargin)
+		if err != nil {
+			return err
+		}
+
+		naming, err := cmd.Flags().GetString(optName)
+		if err != nil {
+			return err
+		}
+
+		idsFile, err := cmd.Flags().GetString(optIDs)
+		if err != nil {
+			return err
+		}
+
+		var ids []string
+		if idsFile != "" {
+			ids, err = composer.LoadIDs(idsFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		imageOut, err := cmd.Flags().GetString(optImageOut)
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString(optOutput)
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString(optFormat)
+		if err != nil {
+			return err
+		}
+
+		fp, err := createOutput(output)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+
+		return composer.Slice(args[0], fp,
+			composer.TileSize(tw, th), composer.Spacing(spacing), composer.Margin(margin),
+			composer.Naming(naming), composer.IDs(ids),
+			composer.ImageOut(imageOut), composer.Output(output), composer.Format(format))
+	},
+}
+
+func init() {
+	sliceCmd.Flags().String(optTile, "", "the tile size (i.e. 16x16, or 16 for square tiles)")
+	sliceCmd.Flags().Int(optSpacing, 0, "the pixels between the tiles")
+	sliceCmd.Flags().Int(optMargin, 0, "the pixels around the tiles")
+	sliceCmd.Flags().String(optName, composer.DefaultNaming, "the tile ids template ({row}, {col} and {index} placeholders)")
+	sliceCmd.Flags().String(optIDs, "", "a file with the tile ids, one per line in reading order (empty cells excluded)")
+	sliceCmd.Flags().String(optImageOut, "", "write the atlas to this PNG file instead of embedding it")
+	sliceCmd.Flags().StringP(optOutput, "o", "", "write the tileset to this file (the image file path is relative to it)")
+	sliceCmd.Flags().String(optFormat, atlas.FormatYAML, "the tileset format (yaml, json, json-array, libgdx or godot)")
+	sliceCmd.MarkFlagRequired(optTile)
+
+	rootCmd.AddCommand(sliceCmd)
+}
+
+// parseTileSize parses a size like '16x16' (or '16').
+func parseTileSize(val string) (width, height int, err error) {
+	parts := strings.SplitN(strings.ToLower(val), "x", 2)
+	if len(parts) == 1 {
+		parts = append(parts, parts[0])
+	}
+
+	width, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err == nil {
+		height, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid tile size %q (i.e. 16x16)", val)
+	}
+
+	return width, height, nil
+}
+
+func sliceCmdExample() string {
+	tpl := `  {{APP}} slice --tile 16x16 sheet.png > sheet.yml
+  {{APP}} slice --tile 16x16 --spacing 1 --name grass_{row}_{col} sheet.png > grass.yml
+  {{APP}} slice --tile 32 --ids names.txt --image-out sheet_atlas.png sheet.png > sheet.yml`
+	return strings.Replace(tpl, "{{APP}}", appName(), -1)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucasepe/tiles/tilemap"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	DisableSuggestions:    true,
+	DisableFlagsInUseLine: true,
+	SilenceUsage:          true,
+	Args:                  cobra.MinimumNArgs(1),
+	Use:                   "validate <tilemap URL or PATH>",
+	Short:                 "Check a tilemap for errors without rendering it",
+	Example:               validateCmdExample(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems, err := tilemap.Validate(args[0])
+		if err != nil {
+			return err
+		}
+
+		for _, el := range problems {
+			if el.Line > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s:%d: %s\n", args[0], el.Line, el.Message)
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", args[0], el.Message)
+			}
+		}
+
+		if len(problems) > 0 {
+			return fmt.Errorf("%d problem(s) found", len(problems))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validateCmdExample() string {
+	tpl := `  {{APP}} validate /path/to/my_map.yml
+  {{APP}} validate https://github.com/lucasepe/tiles/examples/ark.yml`
+
+	return strings.Replace(tpl, "{{APP}}", appName(), -1)
+}
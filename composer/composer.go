@@ -3,25 +3,84 @@ package composer
 import (
 	"bytes"
 	"encoding/base64"
+	"fmt"
 	"image"
 	"image/draw"
 	"image/png"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/lucasepe/tiles/atlas"
 	"github.com/lucasepe/tiles/binpack"
 	"github.com/lucasepe/tiles/data"
 	"github.com/lucasepe/tiles/tileset"
 	"github.com/pkg/errors"
-	"gopkg.in/yaml.v2"
 )
 
+// Options holds the composer settings.
+type Options struct {
+	imageOut string
+	output   string
+	format   string
+	css      string
+	html     string
+	svg      string
+	retina   bool
+
+	tileWidth, tileHeight int
+	spacing, margin       int
+	naming                string
+	ids                   []string
+}
+
+// Format sets the output format: yaml (default), json (TexturePacker
+// JSON Hash), json-array (TexturePacker JSON Array), libgdx or godot;
+// all the formats but yaml need the atlas image file (see ImageOut).
+func Format(name string) func(*Options) {
+	return func(o *Options) {
+		if name != "" {
+			o.format = strings.ToLower(name)
+		}
+	}
+}
+
+// ImageOut writes the atlas image to the specified PNG file,
+// referenced by the tileset 'image' field instead of being
+// embedded as base64 (see Output for the path written).
+func ImageOut(filename string) func(*Options) {
+	return func(o *Options) {
+		o.imageOut = filename
+	}
+}
+
+// Output sets the path of the tileset file, so that the atlas image
+// file is referenced relative to it; if not set, the tileset is
+// expected in the current directory.
+func Output(filename string) func(*Options) {
+	return func(o *Options) {
+		o.output = filename
+	}
+}
+
 // Do generates a tileset from the image
 // list and print the result to the specified writer.
-func Do(il []string, wr io.Writer) error {
+func Do(il []string, wr io.Writer, opts ...func(*Options)) error {
+	cfg := Options{format: atlas.FormatYAML}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := atlas.CheckFormat(cfg.format); err != nil {
+		return err
+	}
+	if atlas.NeedsImageFile(cfg.format) && cfg.imageOut == "" {
+		return fmt.Errorf("the %s format needs the atlas image file", cfg.format)
+	}
+
 	items, err := decodeImageList(il)
 	if err != nil {
 		return err
@@ -35,7 +94,17 @@ func Do(il []string, wr io.Writer) error {
 		return err
 	}
 
-	return bl.dump(wr)
+	if cfg.imageOut != "" {
+		if err := ioutil.WriteFile(cfg.imageOut, bl.data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := bl.writeSprites(cfg); err != nil {
+		return err
+	}
+
+	return bl.dump(wr, cfg)
 }
 
 // block holds tile position,
@@ -85,13 +154,18 @@ func (bl *blockList) createPNG() error {
 	return nil
 }
 
-func (bl *blockList) dump(wr io.Writer) error {
+func (bl *blockList) dump(wr io.Writer, cfg Options) error {
 
 	res := tileset.Tileset{
 		Width:  bl.width,
 		Height: bl.height,
 		Tiles:  make([]*tileset.Tile, len(bl.blocks)),
-		Data:   data.Wrap(base64.StdEncoding.EncodeToString(bl.data), 76),
+	}
+
+	if cfg.imageOut != "" {
+		res.ImageFile = cfg.imageFile()
+	} else {
+		res.Data = data.Wrap(base64.StdEncoding.EncodeToString(bl.data), 76)
 	}
 
 	for i, el := range bl.blocks {
@@ -102,12 +176,16 @@ func (bl *blockList) dump(wr io.Writer) error {
 		}
 	}
 
-	dat, err := yaml.Marshal(&res)
-	if err != nil {
-		return err
+	return atlas.Encode(wr, &res, cfg.format)
+}
+
+// imageFile returns the path of the atlas image file
+// relative to the tileset (see Output).
+func (cfg Options) imageFile() string {
+	if cfg.output == "" {
+		return filepath.ToSlash(cfg.imageOut)
 	}
-	_, err = wr.Write(dat)
-	return err
+	return relativeURL(cfg.output, cfg.imageOut)
 }
 
 // Len returns the number of blocks in total.
@@ -0,0 +1,219 @@
+package composer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/atlas"
+	"github.com/pkg/errors"
+)
+
+// DefaultNaming is the default template of the sliced tiles ids.
+const DefaultNaming = "tile_{row}_{col}"
+
+// TileSize sets the size of the tiles in the sprite sheet (see Slice).
+func TileSize(width, height int) func(*Options) {
+	return func(o *Options) {
+		o.tileWidth, o.tileHeight = width, height
+	}
+}
+
+// Spacing sets the pixels between the tiles in the sprite sheet.
+func Spacing(val int) func(*Options) {
+	return func(o *Options) {
+		o.spacing = val
+	}
+}
+
+// Margin sets the pixels around the tiles in the sprite sheet.
+func Margin(val int) func(*Options) {
+	return func(o *Options) {
+		o.margin = val
+	}
+}
+
+// Naming sets the template of the sliced tiles ids; {row} and {col}
+// are replaced by the tile cell in the sheet, {index} by the tile
+// number (empty cells are not counted).
+func Naming(tpl string) func(*Options) {
+	return func(o *Options) {
+		if tpl != "" {
+			o.naming = tpl
+		}
+	}
+}
+
+// IDs sets the ids of the sliced tiles, in reading order (empty
+// cells excluded); it takes precedence over the naming template.
+func IDs(list []string) func(*Options) {
+	return func(o *Options) {
+		o.ids = list
+	}
+}
+
+// LoadIDs reads an id list file: one id per line,
+// blank lines and lines starting with '#' are skipped.
+func LoadIDs(filename string) ([]string, error) {
+	fp, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	res := []string{}
+
+	sc := bufio.NewScanner(fp)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		res = append(res, line)
+	}
+
+	return res, sc.Err()
+}
+
+// Slice generates a tileset cutting the specified sprite sheet into
+// tiles of fixed size (see TileSize), skipping the fully transparent
+// ones, and print the result to the specified writer; the sheet is
+// the atlas image, so the tiles keep their position.
+func Slice(sheet string, wr io.Writer, opts ...func(*Options)) error {
+	cfg := Options{format: atlas.FormatYAML, naming: DefaultNaming}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if err := atlas.CheckFormat(cfg.format); err != nil {
+		return err
+	}
+	if atlas.NeedsImageFile(cfg.format) && cfg.imageOut == "" {
+		return fmt.Errorf("the %s format needs the atlas image file", cfg.format)
+	}
+	if cfg.tileWidth <= 0 || cfg.tileHeight <= 0 {
+		return fmt.Errorf("invalid tile size %dx%d", cfg.tileWidth, cfg.tileHeight)
+	}
+	if cfg.spacing < 0 || cfg.margin < 0 {
+		return fmt.Errorf("spacing and margin can not be negative")
+	}
+
+	fp, err := os.Open(sheet)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	img, _, err := image.Decode(fp)
+	if err != nil {
+		return errors.Wrapf(err, "image <%s>", sheet)
+	}
+
+	bl, err := sliceImage(img, cfg)
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return err
+	}
+	bl.data = buf.Bytes()
+
+	if cfg.imageOut != "" {
+		if err := ioutil.WriteFile(cfg.imageOut, bl.data, 0644); err != nil {
+			return err
+		}
+	}
+
+	if err := bl.writeSprites(cfg); err != nil {
+		return err
+	}
+
+	return bl.dump(wr, cfg)
+}
+
+// sliceImage returns the blocks of the non empty tiles of the sheet.
+func sliceImage(img image.Image, cfg Options) (*blockList, error) {
+	b := img.Bounds()
+
+	cols := (b.Dx() - 2*cfg.margin + cfg.spacing) / (cfg.tileWidth + cfg.spacing)
+	rows := (b.Dy() - 2*cfg.margin + cfg.spacing) / (cfg.tileHeight + cfg.spacing)
+	if cols <= 0 || rows <= 0 {
+		return nil, fmt.Errorf("the sheet (%dx%d) is smaller than a tile", b.Dx(), b.Dy())
+	}
+
+	bl := &blockList{width: b.Dx(), height: b.Dy()}
+	seen := map[string]bool{}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			x := cfg.margin + col*(cfg.tileWidth+cfg.spacing)
+			y := cfg.margin + row*(cfg.tileHeight+cfg.spacing)
+
+			r := image.Rect(x, y, x+cfg.tileWidth, y+cfg.tileHeight).Add(b.Min)
+			if isTransparent(img, r) {
+				continue
+			}
+
+			idx := len(bl.blocks)
+
+			var id string
+			if len(cfg.ids) > 0 {
+				if idx >= len(cfg.ids) {
+					return nil, fmt.Errorf("the id list is too short: no id for the tile at row %d, col %d", row, col)
+				}
+				id = cfg.ids[idx]
+			} else {
+				id = strings.NewReplacer(
+					"{row}", strconv.Itoa(row),
+					"{col}", strconv.Itoa(col),
+					"{index}", strconv.Itoa(idx),
+				).Replace(cfg.naming)
+			}
+
+			if seen[id] {
+				return nil, fmt.Errorf("duplicate tile id %q", id)
+			}
+			seen[id] = true
+
+			bl.blocks = append(bl.blocks, &block{
+				id: id,
+				x:  x, y: y,
+				w: cfg.tileWidth, h: cfg.tileHeight,
+			})
+		}
+	}
+
+	if len(bl.blocks) == 0 {
+		return nil, fmt.Errorf("the sheet has no tiles (all cells are transparent)")
+	}
+
+	if len(cfg.ids) > len(bl.blocks) {
+		return nil, fmt.Errorf("the id list has %d ids but the sheet has %d tiles", len(cfg.ids), len(bl.blocks))
+	}
+
+	return bl, nil
+}
+
+// isTransparent returns true if all the pixels in the rectangle are
+// fully transparent.
+func isTransparent(img image.Image, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
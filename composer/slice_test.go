@@ -0,0 +1,116 @@
+package composer
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lucasepe/tiles/atlas"
+	"github.com/lucasepe/tiles/binpack"
+)
+
+// sampleSheet returns a 2x2 sheet of 8x8 tiles with 1 pixel of
+// margin and 2 of spacing; the bottom right tile is transparent.
+func sampleSheet() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 1+8+2+8+1, 1+8+2+8+1))
+	for _, pt := range []image.Point{{1, 1}, {11, 1}, {1, 11}} {
+		r := image.Rect(pt.X, pt.Y, pt.X+8, pt.Y+8)
+		draw.Draw(img, r, image.NewUniform(color.NRGBA{0xd3, 0x2f, 0x2f, 0xff}), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestSliceImage(t *testing.T) {
+	cfg := Options{tileWidth: 8, tileHeight: 8, spacing: 2, margin: 1}
+
+	tests := []struct {
+		naming string
+		ids    []string
+		want   []string
+		ok     bool
+	}{
+		{DefaultNaming, nil, []string{"tile_0_0", "tile_0_1", "tile_1_0"}, true},
+		{"grass_{index}", nil, []string{"grass_0", "grass_1", "grass_2"}, true},
+		{"", []string{"a", "b", "c"}, []string{"a", "b", "c"}, true},
+		{"", []string{"a", "b"}, nil, false},
+		{"", []string{"a", "b", "c", "d"}, nil, false},
+		{"grass", nil, nil, false},
+	}
+
+	for _, tt := range tests {
+		cfg.naming, cfg.ids = tt.naming, tt.ids
+
+		bl, err := sliceImage(sampleSheet(), cfg)
+		if got := err == nil; got != tt.ok {
+			t.Errorf("got [%v] want [%v] (%v)", got, tt.ok, err)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+
+		if got, want := len(bl.blocks), len(tt.want); got != want {
+			t.Fatalf("got [%v] want [%v]", got, want)
+		}
+		for i, el := range bl.blocks {
+			if el.id != tt.want[i] {
+				t.Errorf("got [%v] want [%v]", el.id, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestSliceImagePositions(t *testing.T) {
+	cfg := Options{tileWidth: 8, tileHeight: 8, spacing: 2, margin: 1, naming: DefaultNaming}
+
+	bl, err := sliceImage(sampleSheet(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []image.Point{{1, 1}, {11, 1}, {1, 11}}
+	for i, el := range bl.blocks {
+		if got := image.Pt(el.x, el.y); got != want[i] {
+			t.Errorf("got [%v] want [%v]", got, want[i])
+		}
+	}
+
+	if bl.width != 20 || bl.height != 20 {
+		t.Errorf("got [%dx%d] want [20x20]", bl.width, bl.height)
+	}
+}
+
+func TestGodotMixedSizes(t *testing.T) {
+	bl := blockList{blocks: []*block{
+		{id: "a", w: 30, h: 30},
+		{id: "b", w: 32, h: 32},
+		{id: "c", w: 20, h: 24},
+	}}
+	sort.Sort(byMaxOfWidthAndHeight(bl.blocks))
+	bl.width, bl.height = binpack.Pack(&bl)
+
+	cfg := Options{format: atlas.FormatGodot, imageOut: "atlas.png"}
+	if err := bl.dump(&bytes.Buffer{}, cfg); err == nil {
+		t.Errorf("expected an error for tiles not on a common grid")
+	}
+
+	// a sprite sheet is on the grid, margins and spacing included
+	sheet, err := sliceImage(sampleSheet(), Options{tileWidth: 8, tileHeight: 8, spacing: 2, margin: 1, naming: DefaultNaming})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := sheet.dump(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, el := range []string{"margins = Vector2i(1, 1)", "separation = Vector2i(2, 2)", `0:1/0/custom_data_0 = "tile_1_0"`} {
+		if !strings.Contains(buf.String(), el) {
+			t.Errorf("got [%v] want [%v]", buf.String(), el)
+		}
+	}
+}
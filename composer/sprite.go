@@ -0,0 +1,245 @@
+package composer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CSS writes a stylesheet to the specified file with one class per
+// tile ('tile-' followed by the tile id) for the CSS sprites
+// technique; the atlas image is referenced relative to the
+// stylesheet (see ImageOut) or embedded as a data URI.
+func CSS(filename string) func(*Options) {
+	return func(o *Options) {
+		o.css = filename
+	}
+}
+
+// HTML writes a page to the specified file showing all the tiles
+// using the stylesheet (see CSS); if no stylesheet file has been
+// requested the styles are embedded in the page.
+func HTML(filename string) func(*Options) {
+	return func(o *Options) {
+		o.html = filename
+	}
+}
+
+// SVG writes to the specified file an SVG sprite with one
+// symbol per tile (the symbol id is the tile id), to be used
+// like <svg><use href="sprite.svg#aws_lambda"/></svg>.
+func SVG(filename string) func(*Options) {
+	return func(o *Options) {
+		o.svg = filename
+	}
+}
+
+// Retina treats the source images as high density (2x) ones: the
+// stylesheet shows them at half size using an 'image-set'.
+func Retina(enabled bool) func(*Options) {
+	return func(o *Options) {
+		o.retina = enabled
+	}
+}
+
+// writeSprites writes the requested stylesheet,
+// preview page and SVG sprite files.
+func (bl *blockList) writeSprites(cfg Options) error {
+	if cfg.css != "" {
+		err := writeFile(cfg.css, func(wr io.Writer) error {
+			return bl.stylesheet(wr, bl.imageURL(cfg, cfg.css), cfg.retina)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.html != "" {
+		err := writeFile(cfg.html, func(wr io.Writer) error {
+			return bl.preview(wr, cfg)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.svg != "" {
+		return writeFile(cfg.svg, bl.sprite)
+	}
+
+	return nil
+}
+
+// stylesheet writes the CSS rules that show the tiles of the atlas
+// image at the specified url; retina halves all the dimensions.
+func (bl *blockList) stylesheet(wr io.Writer, url string, retina bool) error {
+	scale := 1.0
+	if retina {
+		scale = 0.5
+	}
+
+	bw := bufio.NewWriter(wr)
+
+	fmt.Fprintln(bw, ".tile {")
+	fmt.Fprintln(bw, "  display: inline-block;")
+	fmt.Fprintf(bw, "  background-image: url(%q);\n", url)
+	if retina {
+		fmt.Fprintf(bw, "  background-image: -webkit-image-set(url(%q) 2x);\n", url)
+		fmt.Fprintf(bw, "  background-image: image-set(url(%q) 2x);\n", url)
+		fmt.Fprintf(bw, "  background-size: %s %s;\n",
+			cssPixels(scale*float64(bl.width)), cssPixels(scale*float64(bl.height)))
+	}
+	fmt.Fprintln(bw, "  background-repeat: no-repeat;")
+	fmt.Fprintln(bw, "}")
+
+	for _, el := range bl.blocks {
+		fmt.Fprintf(bw, "\n.%s {\n", cssClass(el.id))
+		fmt.Fprintf(bw, "  width: %s;\n", cssPixels(scale*float64(el.w)))
+		fmt.Fprintf(bw, "  height: %s;\n", cssPixels(scale*float64(el.h)))
+		fmt.Fprintf(bw, "  background-position: %s %s;\n",
+			cssPixels(-scale*float64(el.x)), cssPixels(-scale*float64(el.y)))
+		fmt.Fprintln(bw, "}")
+	}
+
+	return bw.Flush()
+}
+
+// preview writes an HTML page showing all the tiles with their ids.
+func (bl *blockList) preview(wr io.Writer, cfg Options) error {
+	bw := bufio.NewWriter(wr)
+
+	fmt.Fprintln(bw, "<!DOCTYPE html>")
+	fmt.Fprintln(bw, "<html>")
+	fmt.Fprintln(bw, "<head>")
+	fmt.Fprintln(bw, `<meta charset="utf-8">`)
+	fmt.Fprintln(bw, "<title>Tileset preview</title>")
+	if cfg.css != "" {
+		fmt.Fprintf(bw, "<link rel=\"stylesheet\" href=\"%s\">\n", html.EscapeString(relativeURL(cfg.html, cfg.css)))
+	} else {
+		fmt.Fprintln(bw, "<style>")
+		if err := bl.stylesheet(bw, bl.imageURL(cfg, cfg.html), cfg.retina); err != nil {
+			return err
+		}
+		fmt.Fprintln(bw, "</style>")
+	}
+	fmt.Fprintln(bw, "<style>")
+	fmt.Fprintln(bw, "body { font-family: sans-serif; }")
+	fmt.Fprintln(bw, "figure { display: inline-block; margin: 8px; text-align: center; vertical-align: top; }")
+	fmt.Fprintln(bw, "figcaption { font-size: 12px; margin-top: 4px; }")
+	fmt.Fprintln(bw, "</style>")
+	fmt.Fprintln(bw, "</head>")
+	fmt.Fprintln(bw, "<body>")
+
+	for _, el := range bl.blocks {
+		id := html.EscapeString(el.id)
+		fmt.Fprintf(bw, "<figure><span class=\"tile %s\"></span><figcaption>%s</figcaption></figure>\n",
+			cssClass(el.id), id)
+	}
+
+	fmt.Fprintln(bw, "</body>")
+	fmt.Fprintln(bw, "</html>")
+
+	return bw.Flush()
+}
+
+// sprite writes an SVG sprite with one symbol per tile,
+// each one embedding its own image as a PNG data URI.
+func (bl *blockList) sprite(wr io.Writer) error {
+	sheet, err := png.Decode(bytes.NewReader(bl.data))
+	if err != nil {
+		return err
+	}
+
+	sub, ok := sheet.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return fmt.Errorf("atlas image can not be sliced")
+	}
+
+	bw := bufio.NewWriter(wr)
+
+	fmt.Fprintln(bw, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`)
+
+	for _, el := range bl.blocks {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, sub.SubImage(image.Rect(el.x, el.y, el.x+el.w, el.y+el.h))); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, "  <symbol id=\"%s\" viewBox=\"0 0 %d %d\">\n", html.EscapeString(el.id), el.w, el.h)
+		fmt.Fprintf(bw, "    <image width=\"%d\" height=\"%d\" xlink:href=\"data:image/png;base64,%s\"/>\n",
+			el.w, el.h, base64.StdEncoding.EncodeToString(buf.Bytes()))
+		fmt.Fprintln(bw, "  </symbol>")
+	}
+
+	fmt.Fprintln(bw, "</svg>")
+
+	return bw.Flush()
+}
+
+// imageURL returns the url of the atlas image as seen from
+// the specified file, or a data URI if it is embedded.
+func (bl *blockList) imageURL(cfg Options, from string) string {
+	if cfg.imageOut != "" {
+		return relativeURL(from, cfg.imageOut)
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(bl.data)
+}
+
+// relativeURL returns the path of the target file
+// relative to the folder of the specified file.
+func relativeURL(from, target string) string {
+	rel, err := filepath.Rel(filepath.Dir(from), target)
+	if err != nil {
+		rel = filepath.Base(target)
+	}
+
+	return filepath.ToSlash(rel)
+}
+
+// cssClass returns the class name of the tile with the specified
+// id; the characters not allowed in a CSS identifier become '-'.
+func cssClass(id string) string {
+	return "tile-" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, id)
+}
+
+// cssPixels formats a length in pixels.
+func cssPixels(val float64) string {
+	if val == 0 {
+		return "0"
+	}
+
+	return strconv.FormatFloat(val, 'f', -1, 64) + "px"
+}
+
+// writeFile creates the specified file and writes it using fn.
+func writeFile(filename string, fn func(wr io.Writer) error) error {
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(fp); err != nil {
+		fp.Close()
+		return err
+	}
+
+	return fp.Close()
+}
@@ -0,0 +1,82 @@
+package composer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStylesheet(t *testing.T) {
+	bl := blockList{
+		width: 64, height: 32,
+		blocks: []*block{
+			{id: "grass", x: 0, y: 0, w: 32, h: 32},
+			{id: "big tree", x: 32, y: 0, w: 32, h: 20},
+		},
+	}
+
+	tests := []struct {
+		retina bool
+		want   []string
+	}{
+		{false, []string{
+			`background-image: url("sheet.png");`,
+			".tile-grass {\n  width: 32px;\n  height: 32px;\n  background-position: 0 0;\n}",
+			".tile-big-tree {\n  width: 32px;\n  height: 20px;\n  background-position: -32px 0;\n}",
+		}},
+		{true, []string{
+			`background-image: image-set(url("sheet.png") 2x);`,
+			"background-size: 32px 16px;",
+			".tile-big-tree {\n  width: 16px;\n  height: 10px;\n  background-position: -16px 0;\n}",
+		}},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := bl.stylesheet(&buf, "sheet.png", tt.retina); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, el := range tt.want {
+			if !strings.Contains(buf.String(), el) {
+				t.Errorf("got [%v] want [%v]", buf.String(), el)
+			}
+		}
+	}
+}
+
+func TestRelativeURL(t *testing.T) {
+	tests := []struct {
+		from, target string
+		want         string
+	}{
+		{"icons.css", "icons.png", "icons.png"},
+		{"web/css/icons.css", "web/img/icons.png", "../img/icons.png"},
+		{"/tmp/icons.css", "icons.png", "icons.png"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeURL(tt.from, tt.target); got != tt.want {
+			t.Errorf("got [%v] want [%v]", got, tt.want)
+		}
+	}
+}
+
+func TestImageFile(t *testing.T) {
+	tests := []struct {
+		output, imageOut string
+		want             string
+	}{
+		{"", "atlas.png", "atlas.png"},
+		{"", "out/atlas.png", "out/atlas.png"},
+		{"out/atlas.yml", "out/atlas.png", "atlas.png"},
+		{"tilesets/atlas.yml", "img/atlas.png", "../img/atlas.png"},
+	}
+
+	for _, tt := range tests {
+		cfg := Options{output: tt.output, imageOut: tt.imageOut}
+		if got := cfg.imageFile(); got != tt.want {
+			t.Errorf("got [%v] want [%v]", got, tt.want)
+		}
+	}
+}
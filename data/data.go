@@ -4,7 +4,9 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -54,6 +56,29 @@ func FetchFromFile(filename string, limit int64) ([]byte, error) {
 	return ioutil.ReadAll(fp)
 }
 
+// Resolve returns the location of 'ref' relative to the
+// specified 'base' URI (a remote URL or a local file).
+// Absolute references are returned unchanged.
+func Resolve(base, ref string) string {
+	if strings.HasPrefix(ref, "http") || filepath.IsAbs(ref) {
+		return ref
+	}
+
+	if strings.HasPrefix(base, "http") {
+		bu, err := url.Parse(base)
+		if err != nil {
+			return ref
+		}
+		ru, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return bu.ResolveReference(ru).String()
+	}
+
+	return filepath.Join(filepath.Dir(base), ref)
+}
+
 // Wrap hard wrap text at the specified colBreak column.
 func Wrap(text string, colBreak int) string {
 	if colBreak < 1 {
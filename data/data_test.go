@@ -41,3 +41,24 @@ func TestFetchFromFile(t *testing.T) {
 func flatten(s string) string {
 	return strings.Replace((strings.Replace(s, "\n", "", -1)), "\t", "", -1)
 }
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		base string
+		ref  string
+		want string
+	}{
+		{"../examples/map.tmx", "tiles.tsx", "../examples/tiles.tsx"},
+		{"../examples/map.tmx", "/tmp/tiles.tsx", "/tmp/tiles.tsx"},
+		{"https://example.com/maps/map.tmx", "../img/atlas.png", "https://example.com/img/atlas.png"},
+		{"https://example.com/maps/map.tmx", "http://cdn.com/a.png", "http://cdn.com/a.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := Resolve(tt.base, tt.ref); got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package diagram
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/data"
+	"github.com/lucasepe/tiles/tilemap"
+	"gopkg.in/yaml.v2"
+)
+
+// Diagram is a set of nodes (tiles) connected by edges;
+// the nodes are placed on the grid by the layout engine.
+type Diagram struct {
+	direction string
+	spacing   int
+	tileSize  int
+	margin    int
+	bgColor   string
+	font      string
+	watermark interface{}
+	atlasList []string
+	nodes     []node
+	edges     []edge
+
+	// tileAttr is the DOT node attribute holding the tile id
+	tileAttr string
+}
+
+// node is a tile of the diagram.
+type node struct {
+	id    string
+	tile  string
+	label string
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// A node is an object like:
+//
+//	{ id: api, tile: aws_lambda, label: orders api }
+func (nd *node) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		ID    string `yaml:"id"`
+		Tile  string `yaml:"tile"`
+		Label string `yaml:"label"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	nd.id = aux.ID
+	nd.tile = aux.Tile
+	nd.label = aux.Label
+
+	return nil
+}
+
+// edge is a connection between two nodes.
+type edge struct {
+	from   string
+	to     string
+	label  string
+	color  string
+	width  float64
+	dashes float64
+	arrow  string
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// An edge is an object like:
+//
+//	{ from: api, to: db, label: SQL, color: "#333", dashes: 4, arrow: both }
+func (ed *edge) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		From   string  `yaml:"from"`
+		To     string  `yaml:"to"`
+		Label  string  `yaml:"label"`
+		Color  string  `yaml:"color"`
+		Width  float64 `yaml:"width"`
+		Dashes float64 `yaml:"dashes"`
+		Arrow  string  `yaml:"arrow"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	ed.from = aux.From
+	ed.to = aux.To
+	ed.label = aux.Label
+	ed.color = aux.Color
+	ed.width = aux.Width
+	ed.dashes = aux.Dashes
+	ed.arrow = aux.Arrow
+
+	return nil
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+func (d *Diagram) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		Direction string      `yaml:"direction"`
+		Spacing   *int        `yaml:"spacing"`
+		TileSize  int         `yaml:"tile_size"`
+		Margin    int         `yaml:"margin"`
+		BgColor   string      `yaml:"bg_color"`
+		Font      string      `yaml:"font"`
+		Watermark interface{} `yaml:"watermark"`
+		AtlasList []string    `yaml:"atlas_list"`
+		Nodes     []node      `yaml:"nodes"`
+		Edges     []edge      `yaml:"edges"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	d.direction = strings.ToLower(aux.Direction)
+	switch d.direction {
+	case "":
+		d.direction = TopToBottom
+	case TopToBottom, LeftToRight, BottomToTop, RightToLeft:
+	default:
+		return fmt.Errorf("invalid direction %q (TB, LR, BT or RL)", aux.Direction)
+	}
+
+	d.spacing = 1
+	if aux.Spacing != nil {
+		d.spacing = *aux.Spacing
+	}
+	if d.spacing < 0 {
+		return fmt.Errorf("spacing must not be negative")
+	}
+
+	d.tileSize = aux.TileSize
+	d.margin = aux.Margin
+	d.bgColor = aux.BgColor
+	d.font = aux.Font
+	d.watermark = aux.Watermark
+	d.atlasList = aux.AtlasList
+	d.nodes = aux.Nodes
+	d.edges = aux.Edges
+
+	return d.check()
+}
+
+// check verifies that the node ids are valid and
+// unique and that the edges connect known nodes.
+func (d *Diagram) check() error {
+	ids := make(map[string]bool, len(d.nodes))
+	for _, el := range d.nodes {
+		if el.id == "" || el.id == "." || el.id == "_" || strings.ContainsAny(el.id, ":, \t") {
+			return fmt.Errorf("invalid node id %q", el.id)
+		}
+		if _, err := strconv.Atoi(el.id); err == nil {
+			return fmt.Errorf("invalid node id %q: a number would be a tile index", el.id)
+		}
+		if el.tile == "" {
+			return fmt.Errorf("node %q: missing tile", el.id)
+		}
+		if ids[el.id] {
+			return fmt.Errorf("duplicate node id %q", el.id)
+		}
+		ids[el.id] = true
+	}
+
+	for _, el := range d.edges {
+		for _, id := range []string{el.from, el.to} {
+			if !ids[id] {
+				return fmt.Errorf("edge from %q to %q: node %q not found", el.from, el.to, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// TileMap lays out the diagram and returns the
+// equivalent tilemap (YAML).
+func (d *Diagram) TileMap() ([]byte, error) {
+	ids := make([]string, len(d.nodes))
+	for i, el := range d.nodes {
+		ids[i] = el.id
+	}
+
+	links := make([][2]string, len(d.edges))
+	for i, el := range d.edges {
+		links[i] = [2]string{el.from, el.to}
+	}
+
+	pl := layout(ids, links, d.direction, d.spacing)
+
+	// labels are drawn below the nodes: keeps room for the last row ones
+	for _, el := range d.nodes {
+		if el.label != "" && pl.cells[el.id][0] == pl.rows-1 {
+			pl.rows++
+			break
+		}
+	}
+
+	type connectionSpec struct {
+		From   string  `yaml:"from"`
+		To     string  `yaml:"to"`
+		Label  string  `yaml:"label,omitempty"`
+		Color  string  `yaml:"color,omitempty"`
+		Width  float64 `yaml:"width,omitempty"`
+		Dashes float64 `yaml:"dashes,omitempty"`
+		Arrow  string  `yaml:"arrow,omitempty"`
+	}
+
+	res := struct {
+		Cols        int               `yaml:"cols"`
+		Rows        int               `yaml:"rows"`
+		TileSize    int               `yaml:"tile_size"`
+		Margin      int               `yaml:"margin,omitempty"`
+		BgColor     string            `yaml:"bg_color,omitempty"`
+		Font        string            `yaml:"font,omitempty"`
+		Watermark   interface{}       `yaml:"watermark,omitempty"`
+		AtlasList   []string          `yaml:"atlas_list"`
+		Aliases     map[string]string `yaml:"aliases"`
+		Layout      []string          `yaml:"layout"`
+		Cells       map[string][]int  `yaml:"cells,flow"`
+		Labels      map[string]string `yaml:"labels,omitempty"`
+		Connections []connectionSpec  `yaml:"connections,omitempty"`
+	}{
+		Cols:      pl.cols,
+		Rows:      pl.rows,
+		TileSize:  d.tileSize,
+		Margin:    d.margin,
+		BgColor:   d.bgColor,
+		Font:      d.font,
+		Watermark: d.watermark,
+		AtlasList: d.atlasList,
+		Aliases:   make(map[string]string),
+		Cells:     make(map[string][]int),
+		Labels:    make(map[string]string),
+	}
+
+	cells := make([][]string, pl.rows)
+	for r := range cells {
+		cells[r] = make([]string, pl.cols)
+		for c := range cells[r] {
+			cells[r][c] = "."
+		}
+	}
+
+	for _, el := range d.nodes {
+		rc := pl.cells[el.id]
+		cells[rc[0]][rc[1]] = el.id
+		res.Aliases[el.id] = el.tile
+		res.Cells[el.id] = []int{rc[0], rc[1]}
+		if el.label != "" {
+			res.Labels[el.id] = el.label
+		}
+	}
+
+	// each row as a space separated string
+	res.Layout = make([]string, pl.rows)
+	for r, row := range cells {
+		res.Layout[r] = strings.Join(row, " ")
+	}
+
+	for _, el := range d.edges {
+		// a self loop has no path to draw (as for the layout)
+		if el.from == el.to {
+			continue
+		}
+
+		res.Connections = append(res.Connections, connectionSpec{
+			From:   el.from,
+			To:     el.to,
+			Label:  el.label,
+			Color:  el.color,
+			Width:  el.width,
+			Dashes: el.dashes,
+			Arrow:  el.arrow,
+		})
+	}
+
+	return yaml.Marshal(res)
+}
+
+// Render lays out the diagram and renders the equivalent tilemap.
+func (d *Diagram) Render(wr io.Writer, opts ...func(*tilemap.TileMap)) error {
+	dat, err := d.TileMap()
+	if err != nil {
+		return err
+	}
+
+	tm := tilemap.TileMap{}
+	if err := yaml.Unmarshal(dat, &tm); err != nil {
+		return err
+	}
+
+	return tm.Render(wr, opts...)
+}
+
+// Load fetches the diagram at the specified uri.
+func Load(uri string) (Diagram, error) {
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return Diagram{}, err
+	}
+
+	res := Diagram{}
+	if err := yaml.Unmarshal(dat, &res); err != nil {
+		return Diagram{}, err
+	}
+
+	return res, nil
+}
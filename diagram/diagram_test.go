@@ -0,0 +1,236 @@
+package diagram
+
+import (
+	"testing"
+
+	"github.com/lucasepe/tiles/tilemap"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRank(t *testing.T) {
+	nodes := []string{"a", "b", "c", "d"}
+	// c -> a closes a cycle and is ignored
+	edges := [][2]string{{"a", "b"}, {"b", "c"}, {"a", "c"}, {"c", "a"}}
+
+	got := rank(nodes, edges)
+	want := map[string]int{"a": 0, "b": 1, "c": 2, "d": 0}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got [%v] want [%v]", k, got[k], v)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	nodes := []string{"gw", "x", "y", "a", "b"}
+	// the barycenter puts b (below x) before a (below y)
+	edges := [][2]string{{"gw", "x"}, {"gw", "y"}, {"y", "a"}, {"x", "b"}}
+
+	tests := []struct {
+		direction  string
+		rows, cols int
+		cells      map[string][2]int
+	}{
+		{TopToBottom, 5, 3, map[string][2]int{"gw": {0, 1}, "x": {2, 0}, "y": {2, 2}, "b": {4, 0}, "a": {4, 2}}},
+		{LeftToRight, 3, 5, map[string][2]int{"gw": {1, 0}, "x": {0, 2}, "y": {2, 2}, "b": {0, 4}, "a": {2, 4}}},
+		{BottomToTop, 5, 3, map[string][2]int{"gw": {4, 1}, "x": {2, 0}, "y": {2, 2}, "b": {0, 0}, "a": {0, 2}}},
+		{RightToLeft, 3, 5, map[string][2]int{"gw": {1, 4}, "x": {0, 2}, "y": {2, 2}, "b": {0, 0}, "a": {2, 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			got := layout(nodes, edges, tt.direction, 1)
+			if got.rows != tt.rows || got.cols != tt.cols {
+				t.Errorf("got [%dx%d] want [%dx%d]", got.rows, got.cols, tt.rows, tt.cols)
+			}
+			for k, v := range tt.cells {
+				if got.cells[k] != v {
+					t.Errorf("%s: got [%v] want [%v]", k, got.cells[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshalDiagram(t *testing.T) {
+	tests := []struct {
+		src string
+		ok  bool
+	}{
+		{`nodes: [{ id: a, tile: aws_lambda }]`, true},
+		{`{ direction: diagonal, nodes: [{ id: a, tile: aws_lambda }] }`, false},
+		{`nodes: [{ id: "a:b", tile: aws_lambda }]`, false},
+		{`nodes: [{ id: "a,b", tile: aws_lambda }]`, false},
+		{`nodes: [{ id: a }]`, false},
+		{`nodes: [{ id: "1", tile: aws_lambda }]`, false},
+		{`nodes: [{ id: a, tile: x }, { id: a, tile: y }]`, false},
+		{`{ nodes: [{ id: a, tile: x }], edges: [{ from: a, to: b }] }`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			var dg Diagram
+			err := yaml.Unmarshal([]byte(tt.src), &dg)
+			if got := err == nil; got != tt.ok {
+				t.Errorf("got [%v] want [%v] (%v)", got, tt.ok, err)
+			}
+		})
+	}
+}
+
+func TestTileMap(t *testing.T) {
+	src := `
+direction: LR
+tile_size: 32
+nodes:
+  - { id: api, tile: aws_lambda, label: api }
+  - { id: db, tile: aws_rds, label: db }
+edges:
+  - { from: api, to: db, label: SQL }
+`
+	var dg Diagram
+	if err := yaml.Unmarshal([]byte(src), &dg); err != nil {
+		t.Fatal(err)
+	}
+
+	dat, err := dg.TileMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aux := struct {
+		Cols   int               `yaml:"cols"`
+		Rows   int               `yaml:"rows"`
+		Layout []string          `yaml:"layout"`
+		Cells  map[string][2]int `yaml:"cells"`
+	}{}
+	if err := yaml.Unmarshal(dat, &aux); err != nil {
+		t.Fatal(err)
+	}
+
+	// one extra row for the labels of the last row
+	if aux.Cols != 3 || aux.Rows != 2 {
+		t.Errorf("got [%dx%d] want [3x2]", aux.Cols, aux.Rows)
+	}
+	if got, want := aux.Layout[0], "api . db"; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+	if got, want := aux.Cells["db"], [2]int{0, 2}; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+
+	var tm tilemap.TileMap
+	if err := yaml.Unmarshal(dat, &tm); err != nil {
+		t.Errorf("the tilemap must be valid: %v", err)
+	}
+}
+
+func TestTileMapSelfLoop(t *testing.T) {
+	dg, err := ParseDOT([]byte(`digraph { node [tile=aws_lambda]; a -> a; a -> b }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dat, err := dg.TileMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aux := struct {
+		Connections []struct {
+			From string `yaml:"from"`
+			To   string `yaml:"to"`
+		} `yaml:"connections"`
+	}{}
+	if err := yaml.Unmarshal(dat, &aux); err != nil {
+		t.Fatal(err)
+	}
+
+	// the self loop is not a connection
+	if got, want := len(aux.Connections), 1; got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+	if got := aux.Connections[0]; got.From != "a" || got.To != "b" {
+		t.Errorf("got [%v] want [a -> b]", got)
+	}
+}
+
+func TestParseDOT(t *testing.T) {
+	src := `
+// comment
+digraph G {
+  rankdir=LR
+  node [tile=aws_lambda]
+  gw [tile="aws_api_gateway", label="API\nGateway"];
+  "orders svc" -> db [label=SQL, style=dashed, dir=both]
+  gw -> {"orders svc" users}
+  db [tile=aws_rds, label=<<b>mysql</b>>]
+  /* a cluster */
+  subgraph cluster_0 { rankdir=TB; graph [bgcolor="#ff0000"]; node [tile=aws_elasticache]; cache }
+  users:e -> cache
+}`
+
+	dg, err := ParseDOT([]byte(src), TileSize(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the subgraph attributes do not change the graph ones
+	if dg.direction != LeftToRight || dg.tileSize != 32 || dg.bgColor != "" {
+		t.Errorf("got [%v, %v, %v] want [%v, %v, %v]", dg.direction, dg.tileSize, dg.bgColor, LeftToRight, 32, "")
+	}
+
+	nodes := []node{
+		{"gw", "aws_api_gateway", "API Gateway"},
+		{"orders_svc", "aws_lambda", "orders svc"},
+		{"db", "aws_rds", "mysql"},
+		{"users", "aws_lambda", "users"},
+		{"cache", "aws_elasticache", "cache"},
+	}
+	if got, want := len(dg.nodes), len(nodes); got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+	for i, want := range nodes {
+		if got := dg.nodes[i]; got != want {
+			t.Errorf("got [%v] want [%v]", got, want)
+		}
+	}
+
+	edges := []edge{
+		{from: "orders_svc", to: "db", label: "SQL", dashes: 4, arrow: "both"},
+		{from: "gw", to: "orders_svc"},
+		{from: "gw", to: "users"},
+		{from: "users", to: "cache"},
+	}
+	if got, want := len(dg.edges), len(edges); got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+	for i, want := range edges {
+		if got := dg.edges[i]; got != want {
+			t.Errorf("got [%v] want [%v]", got, want)
+		}
+	}
+
+	// numeric names would be read as tile indexes in the layout
+	num, err := ParseDOT([]byte(`digraph { node [tile=aws_lambda]; 1 -> 2 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []node{{"n1", "aws_lambda", "1"}, {"n2", "aws_lambda", "2"}} {
+		if got := num.nodes[i]; got != want {
+			t.Errorf("got [%v] want [%v]", got, want)
+		}
+	}
+
+	rl, err := ParseDOT([]byte(`digraph { rankdir=RL; node [tile=aws_lambda]; a -> b }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl.direction != RightToLeft {
+		t.Errorf("got [%v] want [%v]", rl.direction, RightToLeft)
+	}
+
+	if _, err := ParseDOT([]byte(`graph { a -- b }`)); err == nil {
+		t.Errorf("expected an error for nodes without tile")
+	}
+}
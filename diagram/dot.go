@@ -0,0 +1,597 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lucasepe/tiles/data"
+	"github.com/lucasepe/tiles/grid"
+)
+
+// TileSize sets the tile size of the diagram.
+func TileSize(size int) func(*Diagram) {
+	return func(d *Diagram) {
+		if size > 0 {
+			d.tileSize = size
+		}
+	}
+}
+
+// AtlasList sets the tilesets used by the diagram.
+func AtlasList(uris ...string) func(*Diagram) {
+	return func(d *Diagram) {
+		d.atlasList = append(d.atlasList, uris...)
+	}
+}
+
+// TileAttr sets the DOT node attribute holding
+// the tile identifier (default 'tile').
+func TileAttr(name string) func(*Diagram) {
+	return func(d *Diagram) {
+		if name != "" {
+			d.tileAttr = name
+		}
+	}
+}
+
+// LoadDOT fetches the Graphviz DOT graph at the
+// specified uri and converts it to a diagram.
+func LoadDOT(uri string, opts ...func(*Diagram)) (Diagram, error) {
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return Diagram{}, err
+	}
+
+	return ParseDOT(dat, opts...)
+}
+
+// ParseDOT converts a Graphviz DOT graph to a diagram: nodes must
+// have the tile attribute (i.e. tile="aws_lambda", can be set for
+// all the nodes with 'node [tile=...]'); the node and edge labels,
+// the edge color, style, direction and pen width and the graph
+// rankdir and bgcolor are mapped too.
+func ParseDOT(src []byte, opts ...func(*Diagram)) (Diagram, error) {
+	res := Diagram{
+		direction: TopToBottom,
+		spacing:   1,
+		tileSize:  64,
+		tileAttr:  "tile",
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+
+	gr, err := parseDOT(string(src))
+	if err != nil {
+		return Diagram{}, err
+	}
+
+	switch dir := strings.ToLower(gr.attrs["rankdir"]); dir {
+	case LeftToRight, BottomToTop, RightToLeft:
+		res.direction = dir
+	}
+
+	if bg := gr.attrs["bgcolor"]; grid.IsHexColor(bg) {
+		res.bgColor = bg
+	}
+
+	ids := make(map[string]string, len(gr.nodes))
+	used := make(map[string]bool, len(gr.nodes))
+	for _, el := range gr.nodes {
+		attrs := gr.nodeAttrs[el]
+
+		tile := attrs[res.tileAttr]
+		if tile == "" {
+			return Diagram{}, fmt.Errorf("node %q: missing %q attribute", el, res.tileAttr)
+		}
+
+		id := safeID(el, used)
+		ids[el] = id
+
+		// as in Graphviz the default label is the node name
+		label, ok := attrs["label"]
+		if !ok {
+			label = `\N`
+		}
+
+		res.nodes = append(res.nodes, node{
+			id:    id,
+			tile:  tile,
+			label: dotLabel(label, el),
+		})
+	}
+
+	for _, el := range gr.edges {
+		ed := edge{
+			from:  ids[el.from],
+			to:    ids[el.to],
+			label: dotLabel(el.attrs["label"], ""),
+		}
+
+		if grid.IsHexColor(el.attrs["color"]) {
+			ed.color = el.attrs["color"]
+		}
+
+		if val, err := strconv.ParseFloat(el.attrs["penwidth"], 64); err == nil {
+			ed.width = val
+		}
+
+		style := el.attrs["style"]
+		if strings.Contains(style, "dashed") {
+			ed.dashes = 4
+		} else if strings.Contains(style, "dotted") {
+			ed.dashes = 2
+		}
+
+		dir := el.attrs["dir"]
+		if dir == "" && !gr.directed {
+			dir = "none"
+		}
+		switch dir {
+		case "back":
+			ed.arrow = "start"
+		case "both", "none":
+			ed.arrow = dir
+		}
+
+		res.edges = append(res.edges, ed)
+	}
+
+	return res, res.check()
+}
+
+// safeID returns a unique node id that can be used
+// as alias and cell name in a tilemap layout (numeric
+// ids get the 'n' prefix).
+func safeID(id string, used map[string]bool) string {
+	res := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == ':' || r == ',' {
+			return '_'
+		}
+		return r
+	}, id)
+
+	if res == "" || res == "." || res == "_" {
+		res = "node" + res
+	}
+
+	// a number in the layout is a mapping index, not an alias
+	if _, err := strconv.Atoi(res); err == nil {
+		res = "n" + res
+	}
+
+	for i, base := 2, res; used[res]; i++ {
+		res = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[res] = true
+
+	return res
+}
+
+// dotLabel converts a DOT label: '\N' is the node
+// name, the line break escapes become spaces.
+func dotLabel(label, name string) string {
+	label = strings.Replace(label, `\N`, name, -1)
+	for _, el := range []string{`\n`, `\l`, `\r`} {
+		label = strings.Replace(label, el, " ", -1)
+	}
+	return strings.TrimSpace(label)
+}
+
+// dotGraph is a parsed DOT graph.
+type dotGraph struct {
+	directed  bool
+	attrs     map[string]string
+	nodes     []string
+	nodeAttrs map[string]map[string]string
+	edges     []dotEdge
+}
+
+// dotEdge is a parsed DOT edge.
+type dotEdge struct {
+	from, to string
+	attrs    map[string]string
+}
+
+// dotScope holds the attributes of a graph or of a subgraph
+// and the default attributes of its nodes and edges.
+type dotScope struct {
+	graph map[string]string
+	node  map[string]string
+	edge  map[string]string
+}
+
+// dotParser is a recursive descent parser
+// of the DOT language (ports are ignored).
+type dotParser struct {
+	toks  []dotToken
+	pos   int
+	graph *dotGraph
+}
+
+// parseDOT parses the first graph in the source.
+func parseDOT(src string) (*dotGraph, error) {
+	toks, err := lexDOT(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dotParser{
+		toks: toks,
+		graph: &dotGraph{
+			attrs:     make(map[string]string),
+			nodeAttrs: make(map[string]map[string]string),
+		},
+	}
+
+	if p.keyword("strict") {
+		p.pos++
+	}
+
+	switch {
+	case p.keyword("digraph"):
+		p.graph.directed = true
+	case p.keyword("graph"):
+	default:
+		return nil, p.errorf("expected 'graph' or 'digraph'")
+	}
+	p.pos++
+
+	if p.peek().kind == tokID {
+		p.pos++
+	}
+
+	scope := &dotScope{graph: p.graph.attrs, node: map[string]string{}, edge: map[string]string{}}
+	if _, err := p.block(scope); err != nil {
+		return nil, err
+	}
+
+	return p.graph, nil
+}
+
+// block parses '{ stmt_list }' and returns the nodes in it.
+func (p *dotParser) block(scope *dotScope) ([]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	nodes := []string{}
+	for !p.punct("}") {
+		if p.peek().kind == tokEOF {
+			return nil, p.errorf("expected '}'")
+		}
+
+		res, err := p.stmt(scope)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, res...)
+
+		if p.punct(";") || p.punct(",") {
+			p.pos++
+		}
+	}
+	p.pos++
+
+	return nodes, nil
+}
+
+// stmt parses a statement and returns the nodes in it.
+func (p *dotParser) stmt(scope *dotScope) ([]string, error) {
+	switch {
+	case p.keyword("graph"), p.keyword("node"), p.keyword("edge"):
+		kind := strings.ToLower(p.next().text)
+		attrs, err := p.attrList()
+		if err != nil {
+			return nil, err
+		}
+		dst := scope.graph
+		if kind == "node" {
+			dst = scope.node
+		} else if kind == "edge" {
+			dst = scope.edge
+		}
+		for k, v := range attrs {
+			dst[k] = v
+		}
+		return nil, nil
+	}
+
+	if p.peek().kind == tokID && p.at(1).text == "=" && p.at(1).kind == tokPunct {
+		key := p.next().text
+		p.pos++
+		val := p.next()
+		if val.kind != tokID {
+			return nil, p.errorf("expected a value for %q", key)
+		}
+		scope.graph[key] = val.text
+		return nil, nil
+	}
+
+	// node or edge statement
+	left, err := p.operand(scope)
+	if err != nil {
+		return nil, err
+	}
+	if !p.punct("->") && !p.punct("--") {
+		attrs, err := p.attrList()
+		if err != nil {
+			return nil, err
+		}
+		for _, el := range left {
+			for k, v := range attrs {
+				p.graph.nodeAttrs[el][k] = v
+			}
+		}
+		return left, nil
+	}
+
+	chain := [][]string{left}
+	nodes := append([]string{}, left...)
+	for p.punct("->") || p.punct("--") {
+		p.pos++
+		right, err := p.operand(scope)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, right)
+		nodes = append(nodes, right...)
+	}
+
+	attrs, err := p.attrList()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(chain); i++ {
+		for _, from := range chain[i-1] {
+			for _, to := range chain[i] {
+				ed := dotEdge{from: from, to: to, attrs: make(map[string]string)}
+				for k, v := range scope.edge {
+					ed.attrs[k] = v
+				}
+				for k, v := range attrs {
+					ed.attrs[k] = v
+				}
+				p.graph.edges = append(p.graph.edges, ed)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// operand parses a node id (with optional port) or a subgraph.
+func (p *dotParser) operand(scope *dotScope) ([]string, error) {
+	if p.keyword("subgraph") || p.punct("{") {
+		if p.keyword("subgraph") {
+			p.pos++
+			if p.peek().kind == tokID {
+				p.pos++
+			}
+		}
+
+		// the subgraph attributes do not apply to the graph
+		inner := &dotScope{graph: map[string]string{}, node: map[string]string{}, edge: map[string]string{}}
+		for k, v := range scope.node {
+			inner.node[k] = v
+		}
+		for k, v := range scope.edge {
+			inner.edge[k] = v
+		}
+		return p.block(inner)
+	}
+
+	tok := p.next()
+	if tok.kind != tokID {
+		return nil, p.errorf("unexpected %q", tok.text)
+	}
+
+	// ports are ignored: id:port[:compass]
+	for p.punct(":") {
+		p.pos += 2
+	}
+
+	if _, ok := p.graph.nodeAttrs[tok.text]; !ok {
+		attrs := make(map[string]string)
+		for k, v := range scope.node {
+			attrs[k] = v
+		}
+		p.graph.nodeAttrs[tok.text] = attrs
+		p.graph.nodes = append(p.graph.nodes, tok.text)
+	}
+
+	return []string{tok.text}, nil
+}
+
+// attrList parses zero or more '[ a = b, ... ]' lists.
+func (p *dotParser) attrList() (map[string]string, error) {
+	res := make(map[string]string)
+	for p.punct("[") {
+		p.pos++
+		for !p.punct("]") {
+			key := p.next()
+			if key.kind != tokID {
+				return nil, p.errorf("expected an attribute name")
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			val := p.next()
+			if val.kind != tokID {
+				return nil, p.errorf("expected a value for %q", key.text)
+			}
+			res[key.text] = val.text
+
+			if p.punct(";") || p.punct(",") {
+				p.pos++
+			}
+		}
+		p.pos++
+	}
+
+	return res, nil
+}
+
+func (p *dotParser) at(i int) dotToken {
+	if p.pos+i < len(p.toks) {
+		return p.toks[p.pos+i]
+	}
+	return dotToken{kind: tokEOF}
+}
+
+func (p *dotParser) peek() dotToken {
+	return p.at(0)
+}
+
+func (p *dotParser) next() dotToken {
+	tok := p.peek()
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword returns true if the next token is the (unquoted) keyword.
+func (p *dotParser) keyword(name string) bool {
+	tok := p.peek()
+	return tok.kind == tokID && !tok.quoted && strings.EqualFold(tok.text, name)
+}
+
+// punct returns true if the next token is the punctuation.
+func (p *dotParser) punct(s string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.text == s
+}
+
+func (p *dotParser) expect(s string) error {
+	if !p.punct(s) {
+		return p.errorf("expected %q", s)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *dotParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("dot: line %d: %s", p.peek().line, fmt.Sprintf(format, args...))
+}
+
+var htmlTagRE = regexp.MustCompile(`<[^>]*>`)
+
+// Token kinds of the DOT lexer.
+const (
+	tokEOF = iota
+	tokID
+	tokPunct
+)
+
+type dotToken struct {
+	kind   int
+	text   string
+	quoted bool
+	line   int
+}
+
+// lexDOT splits the DOT source in tokens: ids (names, numerals,
+// quoted and HTML strings), punctuation and edge operators;
+// comments and preprocessor lines are skipped.
+func lexDOT(src string) ([]dotToken, error) {
+	rs := []rune(src)
+	res := []dotToken{}
+	line := 1
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' && (i == 0 || rs[i-1] == '\n'), r == '/' && i+1 < len(rs) && rs[i+1] == '/':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			i += 2
+			for i < len(rs) && !(rs[i] == '*' && i+1 < len(rs) && rs[i+1] == '/') {
+				if rs[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			i += 2
+		case r == '-' && i+1 < len(rs) && (rs[i+1] == '>' || rs[i+1] == '-'):
+			res = append(res, dotToken{kind: tokPunct, text: string(rs[i : i+2]), line: line})
+			i += 2
+		case strings.ContainsRune("{}[]=;,:", r):
+			res = append(res, dotToken{kind: tokPunct, text: string(r), line: line})
+			i++
+		case r == '"':
+			var sb strings.Builder
+			start := line
+			for i++; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) && rs[i+1] == '"' {
+					i++
+				} else if rs[i] == '\\' && i+1 < len(rs) && rs[i+1] == '\n' {
+					i++
+					line++
+					continue
+				} else if rs[i] == '\n' {
+					line++
+				}
+				sb.WriteRune(rs[i])
+			}
+			if i >= len(rs) {
+				return nil, fmt.Errorf("dot: line %d: unterminated string", start)
+			}
+			i++
+
+			// "a" + "b" concatenation
+			if n := len(res); n >= 2 && res[n-1].kind == tokPunct && res[n-1].text == "+" && res[n-2].quoted {
+				res[n-2].text += sb.String()
+				res = res[:n-1]
+				continue
+			}
+			res = append(res, dotToken{kind: tokID, text: sb.String(), quoted: true, line: start})
+		case r == '+':
+			res = append(res, dotToken{kind: tokPunct, text: "+", line: line})
+			i++
+		case r == '<':
+			start, depth := i, 0
+			for ; i < len(rs); i++ {
+				if rs[i] == '<' {
+					depth++
+				} else if rs[i] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				} else if rs[i] == '\n' {
+					line++
+				}
+			}
+			if i >= len(rs) {
+				return nil, fmt.Errorf("dot: line %d: unterminated HTML string", line)
+			}
+			// HTML-like labels are reduced to plain text
+			text := htmlTagRE.ReplaceAllString(string(rs[start+1:i]), "")
+			res = append(res, dotToken{kind: tokID, text: text, quoted: true, line: line})
+			i++
+		case r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(rs) && (rs[i] == '_' || rs[i] == '.' || unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i]) ||
+				(rs[i] == '-' && i == start)) {
+				i++
+			}
+			res = append(res, dotToken{kind: tokID, text: string(rs[start:i]), line: line})
+		default:
+			return nil, fmt.Errorf("dot: line %d: unexpected character %q", line, r)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,193 @@
+package diagram
+
+import (
+	"sort"
+)
+
+// Layout directions.
+const (
+	// TopToBottom places the layers as rows.
+	TopToBottom = "tb"
+	// LeftToRight places the layers as columns.
+	LeftToRight = "lr"
+	// BottomToTop places the layers as rows, last first.
+	BottomToTop = "bt"
+	// RightToLeft places the layers as columns, last first.
+	RightToLeft = "rl"
+)
+
+// placement is the result of the layout: the grid
+// size and the [row, col] cell of each node.
+type placement struct {
+	rows, cols int
+	cells      map[string][2]int
+}
+
+// layout places the nodes on a grid with a layered (hierarchical)
+// layout: each edge goes from a layer to a following one, the
+// nodes of every layer are ordered to reduce the crossings and
+// centered; spacing is the number of empty cells between nodes.
+func layout(nodes []string, edges [][2]string, direction string, spacing int) placement {
+	layers := order(nodes, edges, rank(nodes, edges))
+
+	width := 0
+	for _, ly := range layers {
+		if len(ly) > width {
+			width = len(ly)
+		}
+	}
+
+	step := spacing + 1
+	res := placement{cells: make(map[string][2]int)}
+	if len(layers) == 0 {
+		return res
+	}
+	res.rows = (len(layers)-1)*step + 1
+	res.cols = (width-1)*step + 1
+
+	reverse := direction == BottomToTop || direction == RightToLeft
+	for i, ly := range layers {
+		if reverse {
+			i = len(layers) - 1 - i
+		}
+		offset := (width - len(ly)) * step / 2
+		for j, id := range ly {
+			res.cells[id] = [2]int{i * step, offset + j*step}
+		}
+	}
+
+	if direction == LeftToRight || direction == RightToLeft {
+		res.rows, res.cols = res.cols, res.rows
+		for id, rc := range res.cells {
+			res.cells[id] = [2]int{rc[1], rc[0]}
+		}
+	}
+
+	return res
+}
+
+// rank assigns a layer to each node: the sources are in the first
+// layer, any other node is one layer below its lowest predecessor
+// (longest path). The edges closing a cycle are ignored.
+func rank(nodes []string, edges [][2]string) map[string]int {
+	succ := make(map[string][]string)
+	for _, el := range edges {
+		if el[0] != el[1] {
+			succ[el[0]] = append(succ[el[0]], el[1])
+		}
+	}
+
+	// depth first visit in declaration order, dropping back edges
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	dag := make(map[string][]string)
+	post := make([]string, 0, len(nodes))
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		for _, next := range succ[id] {
+			if state[next] == visiting {
+				continue
+			}
+			dag[id] = append(dag[id], next)
+			if state[next] == unvisited {
+				visit(next)
+			}
+		}
+		state[id] = visited
+		post = append(post, id)
+	}
+
+	for _, id := range nodes {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+
+	// reverse post order is a topological order
+	res := make(map[string]int, len(nodes))
+	for i := len(post) - 1; i >= 0; i-- {
+		id := post[i]
+		for _, next := range dag[id] {
+			if res[id]+1 > res[next] {
+				res[next] = res[id] + 1
+			}
+		}
+	}
+
+	return res
+}
+
+// order groups the nodes by layer and sorts each layer by the
+// barycenter of the neighbours in the adjacent layers, sweeping
+// down and up a few times; ties keep the declaration order.
+func order(nodes []string, edges [][2]string, ranks map[string]int) [][]string {
+	layers := [][]string{}
+	for _, id := range nodes {
+		r := ranks[id]
+		for len(layers) <= r {
+			layers = append(layers, []string{})
+		}
+		layers[r] = append(layers[r], id)
+	}
+
+	pos := make(map[string]int)
+	for _, ly := range layers {
+		for j, id := range ly {
+			pos[id] = j
+		}
+	}
+
+	// neighbours of each node in the layer above and below
+	above := make(map[string][]string)
+	below := make(map[string][]string)
+	for _, el := range edges {
+		a, b := el[0], el[1]
+		if ranks[a] > ranks[b] {
+			a, b = b, a
+		}
+		if ranks[a] < ranks[b] {
+			below[a] = append(below[a], b)
+			above[b] = append(above[b], a)
+		}
+	}
+
+	sortLayer := func(ly []string, adj map[string][]string) {
+		bc := make(map[string]float64, len(ly))
+		for _, id := range ly {
+			if len(adj[id]) == 0 {
+				bc[id] = float64(pos[id])
+				continue
+			}
+			sum := 0
+			for _, el := range adj[id] {
+				sum += pos[el]
+			}
+			bc[id] = float64(sum) / float64(len(adj[id]))
+		}
+
+		sort.SliceStable(ly, func(i, j int) bool {
+			return bc[ly[i]] < bc[ly[j]]
+		})
+		for j, id := range ly {
+			pos[id] = j
+		}
+	}
+
+	const sweeps = 4
+	for n := 0; n < sweeps; n++ {
+		for i := 1; i < len(layers); i++ {
+			sortLayer(layers[i], above)
+		}
+		for i := len(layers) - 2; i >= 0; i-- {
+			sortLayer(layers[i], below)
+		}
+	}
+
+	return layers
+}
@@ -0,0 +1,118 @@
+package grid
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+)
+
+const (
+	// FormatPNG renders the grid as a PNG raster image.
+	FormatPNG = "png"
+	// FormatSVG renders the grid as an SVG vector image.
+	FormatSVG = "svg"
+)
+
+// Canvas is the drawing backend of the grid.
+// All the coordinates are relative to the grid
+// origin (the top left corner inside the margin).
+type Canvas interface {
+	// Clear fills the whole image (margin included) with the color.
+	Clear(hex string)
+
+	// DrawImage draws the image scaled to the specified rectangle.
+	DrawImage(img image.Image, x, y, w, h float64)
+
+	// DrawPath fills and/or strokes the polyline.
+	DrawPath(pts []gg.Point, closed bool, st Style)
+
+	// DrawRoundedRect fills and/or strokes the rectangle.
+	DrawRoundedRect(x, y, w, h, r float64, st Style)
+
+	// DrawString draws the text at the specified anchor point,
+	// using the same anchoring rules of gg.DrawStringAnchored.
+	DrawString(s string, x, y, ax, ay float64, ts TextStyle)
+
+	// Encode writes the image to the specified writer.
+	Encode(w io.Writer) error
+}
+
+// Style describes how a shape is filled and stroked.
+// Empty colors (or a zero width) disable fill and stroke.
+type Style struct {
+	Fill        string
+	Stroke      string
+	StrokeWidth float64
+	Dashes      float64
+}
+
+// TextStyle describes how a text is drawn; Angle
+// rotates the text clockwise (in degrees) about its anchor point.
+type TextStyle struct {
+	Font  *truetype.Font
+	Size  float64
+	Color string
+	Angle float64
+}
+
+// newCanvas creates the canvas for the specified format.
+func newCanvas(format string, width, height, margin int) (Canvas, error) {
+	switch strings.ToLower(format) {
+	case "", FormatPNG:
+		return newRasterCanvas(width, height, margin), nil
+	case FormatSVG:
+		return newSVGCanvas(width, height, margin), nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// IsHexColor returns true if the string is a color
+// in one of the supported forms: #rgb, #rrggbb or #rrggbbaa.
+func IsHexColor(hex string) bool {
+	if !strings.HasPrefix(hex, "#") {
+		return false
+	}
+
+	hex = hex[1:]
+	switch len(hex) {
+	case 3, 6, 8:
+	default:
+		return false
+	}
+
+	for _, r := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseHexColor decodes a color in one of the forms
+// #rgb, #rrggbb or #rrggbbaa (as accepted by gg).
+func parseHexColor(hex string) color.NRGBA {
+	var r, g, b int
+	a := 255
+
+	hex = strings.TrimPrefix(hex, "#")
+	switch len(hex) {
+	case 3:
+		fmt.Sscanf(hex, "%1x%1x%1x", &r, &g, &b)
+		r |= r << 4
+		g |= g << 4
+		b |= b << 4
+	case 6:
+		fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b)
+	case 8:
+		fmt.Sscanf(hex, "%02x%02x%02x%02x", &r, &g, &b, &a)
+	}
+
+	return color.NRGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: uint8(a)}
+}
@@ -0,0 +1,183 @@
+package grid
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fogleman/gg"
+)
+
+// ConnectorOptions holds the settings used to draw a connector.
+type ConnectorOptions struct {
+	color      string
+	width      float64
+	dashes     float64
+	arrowStart bool
+	arrowEnd   bool
+	label      string
+}
+
+// ConnectorColor sets the connector line color.
+func ConnectorColor(hex string) func(*ConnectorOptions) {
+	return func(co *ConnectorOptions) {
+		if hex != "" {
+			co.color = hex
+		}
+	}
+}
+
+// ConnectorWidth sets the connector line width.
+func ConnectorWidth(val float64) func(*ConnectorOptions) {
+	return func(co *ConnectorOptions) {
+		if val > 0 {
+			co.width = val
+		}
+	}
+}
+
+// ConnectorDashes sets the connector dash length (zero for a solid line).
+func ConnectorDashes(val float64) func(*ConnectorOptions) {
+	return func(co *ConnectorOptions) {
+		co.dashes = val
+	}
+}
+
+// Arrows sets the arrowheads at the start and at the end of the connector.
+func Arrows(start, end bool) func(*ConnectorOptions) {
+	return func(co *ConnectorOptions) {
+		co.arrowStart = start
+		co.arrowEnd = end
+	}
+}
+
+// ConnectorLabel sets the text drawn along the connector.
+func ConnectorLabel(text string) func(*ConnectorOptions) {
+	return func(co *ConnectorOptions) {
+		co.label = text
+	}
+}
+
+// DrawConnector draws a line through the centers of the cells of the
+// path (i.e. computed by Route); the line starts and ends close to the
+// edges of the first and the last cell, so it does not cover their tiles.
+func (g *Grid) DrawConnector(path [][2]int, opts ...func(*ConnectorOptions)) error {
+	co := ConnectorOptions{
+		color:    "#161615",
+		width:    math.Max(1, 0.03*g.CellSize()),
+		arrowEnd: true,
+	}
+	for _, opt := range opts {
+		opt(&co)
+	}
+
+	// the routes move between the four sides of the cells
+	if g.gridType != TypeSquare {
+		return fmt.Errorf("connectors are supported on square grids only, not %s", g.gridType)
+	}
+
+	if len(path) < 2 {
+		return fmt.Errorf("a connector needs at least two cells")
+	}
+
+	for _, el := range path {
+		if err := g.VerifyInBounds(el[0], el[1]); err != nil {
+			return err
+		}
+	}
+
+	pts := make([]gg.Point, 0, len(path))
+	for _, el := range path {
+		pt := g.CellCenter(el[0], el[1])
+		// merge the cells along a straight line
+		if n := len(pts); n >= 2 && collinear(pts[n-2], pts[n-1], pt) {
+			pts[n-1] = pt
+			continue
+		}
+		pts = append(pts, pt)
+	}
+
+	// move the ends towards the cell edges
+	trim := func(end, next gg.Point) gg.Point {
+		dx, dy := next.X-end.X, next.Y-end.Y
+		d := math.Hypot(dx, dy)
+		if d == 0 {
+			return end
+		}
+		// 40% of the cell size along the segment direction
+		n := 0.4 * (math.Abs(dx)*g.CellWidth() + math.Abs(dy)*g.CellHeight()) / d
+		n = math.Min(n, 0.45*d)
+		return gg.Point{X: end.X + dx/d*n, Y: end.Y + dy/d*n}
+	}
+	last := len(pts) - 1
+	pts[0] = trim(pts[0], pts[1])
+	pts[last] = trim(pts[last], pts[last-1])
+
+	g.canvas.DrawPath(pts, false, Style{
+		Stroke:      co.color,
+		StrokeWidth: co.width,
+		Dashes:      co.dashes,
+	})
+
+	if co.arrowStart {
+		g.drawArrowhead(pts[1], pts[0], co)
+	}
+	if co.arrowEnd {
+		g.drawArrowhead(pts[last-1], pts[last], co)
+	}
+
+	if co.label != "" {
+		g.drawConnectorLabel(pts, co)
+	}
+
+	return nil
+}
+
+// drawArrowhead draws a filled triangle pointing
+// to the tip, along the direction from -> tip.
+func (g *Grid) drawArrowhead(from, tip gg.Point, co ConnectorOptions) {
+	dx, dy := tip.X-from.X, tip.Y-from.Y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		return
+	}
+	dx, dy = dx/d, dy/d
+
+	size := math.Max(6, 4*co.width)
+	base := gg.Point{X: tip.X - dx*size, Y: tip.Y - dy*size}
+
+	g.canvas.DrawPath([]gg.Point{
+		tip,
+		{X: base.X - dy*0.5*size, Y: base.Y + dx*0.5*size},
+		{X: base.X + dy*0.5*size, Y: base.Y - dx*0.5*size},
+	}, true, Style{Fill: co.color})
+}
+
+// drawConnectorLabel draws the label at the middle
+// of the longest segment of the connector.
+func (g *Grid) drawConnectorLabel(pts []gg.Point, co ConnectorOptions) {
+	var mid gg.Point
+	longest := -1.0
+	for i := 1; i < len(pts); i++ {
+		if d := math.Hypot(pts[i].X-pts[i-1].X, pts[i].Y-pts[i-1].Y); d > longest {
+			longest = d
+			mid = gg.Point{X: 0.5 * (pts[i].X + pts[i-1].X), Y: 0.5 * (pts[i].Y + pts[i-1].Y)}
+		}
+	}
+
+	ts := TextStyle{
+		Font:  g.font,
+		Size:  0.15 * g.CellSize(),
+		Color: co.color,
+	}
+
+	sw, sh := g.measure(co.label, ts)
+	pad := 0.2 * sh
+	g.canvas.DrawRoundedRect(mid.X-0.5*sw-pad, mid.Y-0.5*sh-pad, sw+2*pad, sh+2*pad, pad,
+		Style{Fill: g.backgroundColor})
+	g.canvas.DrawString(co.label, mid.X, mid.Y, 0.5, 0.35, ts)
+}
+
+// collinear returns true if the three points lie on the same line.
+func collinear(a, b, c gg.Point) bool {
+	return math.Abs((b.X-a.X)*(c.Y-a.Y)-(b.Y-a.Y)*(c.X-a.X)) < 1e-6
+}
@@ -0,0 +1,139 @@
+package grid
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// Fit modes: how an image is sized into the cell (or span) area.
+// If no mode is specified, images larger than the area are
+// shrinked keeping their aspect ratio, smaller ones are untouched.
+const (
+	// FitContain scales the image (up or down) to fit the area
+	// keeping its aspect ratio.
+	FitContain = "contain"
+	// FitCover scales the image to cover the whole area keeping
+	// its aspect ratio, the exceeding parts are cropped.
+	FitCover = "cover"
+	// FitStretch scales the image to the area size.
+	FitStretch = "stretch"
+	// FitNone draws the image at its native size.
+	FitNone = "none"
+	// FitUpscaleNearest scales the image by the largest integer
+	// factor that fits the area using the nearest neighbor filter
+	// (crisp pixel art); larger images are shrinked.
+	FitUpscaleNearest = "upscale-nearest"
+)
+
+// filters are the supported resampling filters.
+var filters = map[string]imaging.ResampleFilter{
+	"lanczos":    imaging.Lanczos,
+	"nearest":    imaging.NearestNeighbor,
+	"linear":     imaging.Linear,
+	"box":        imaging.Box,
+	"catmullrom": imaging.CatmullRom,
+	"mitchell":   imaging.MitchellNetravali,
+}
+
+// CheckFit returns an error if the fit mode or the
+// resampling filter (both optional) are not supported.
+func CheckFit(mode, filter string) error {
+	switch strings.ToLower(mode) {
+	case "", FitContain, FitCover, FitStretch, FitNone, FitUpscaleNearest:
+	default:
+		return fmt.Errorf("invalid fit mode %q", mode)
+	}
+
+	if _, ok := filters[strings.ToLower(filter)]; filter != "" && !ok {
+		return fmt.Errorf("invalid resampling filter %q", filter)
+	}
+
+	return nil
+}
+
+// Fit sets how the image is sized into the cell area
+// (contain, cover, stretch, none or upscale-nearest).
+func Fit(mode string) func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		if mode != "" {
+			do.fit = strings.ToLower(mode)
+		}
+	}
+}
+
+// Filter sets the resampling filter used to resize the image
+// (lanczos, nearest, linear, box, catmullrom or mitchell).
+func Filter(name string) func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		if name != "" {
+			do.filter = strings.ToLower(name)
+		}
+	}
+}
+
+// fit returns the image sized into an area of aw x ah pixels
+// and its destination size. The image is resized here only if
+// a filter is required, otherwise the canvas scales it.
+func fit(img image.Image, aw, ah float64, mode, filter string) (image.Image, float64, float64, error) {
+	if err := CheckFit(mode, filter); err != nil {
+		return nil, 0, 0, err
+	}
+
+	w, h := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	if w == 0 || h == 0 {
+		return img, w, h, nil
+	}
+
+	contain := math.Min(aw/w, ah/h)
+
+	scale := 1.0
+	switch mode {
+	case "":
+		scale = math.Min(1, contain)
+	case FitContain:
+		scale = contain
+	case FitCover:
+		scale = math.Max(aw/w, ah/h)
+	case FitStretch:
+		return resize(img, aw, ah, filter), aw, ah, nil
+	case FitUpscaleNearest:
+		scale = contain
+		if scale >= 1 {
+			scale = math.Floor(scale)
+		}
+		if filter == "" {
+			filter = "nearest"
+		}
+	}
+
+	w, h = math.Round(w*scale), math.Round(h*scale)
+
+	if mode == FitCover {
+		if filter == "" {
+			filter = "lanczos"
+		}
+		img = resize(img, w, h, filter)
+		img = imaging.CropCenter(img, int(math.Min(w, aw)), int(math.Min(h, ah)))
+		return img, math.Min(w, aw), math.Min(h, ah), nil
+	}
+
+	return resize(img, w, h, filter), w, h, nil
+}
+
+// resize scales the image with the specified filter;
+// with no filter the image is returned as is.
+func resize(img image.Image, w, h float64, filter string) image.Image {
+	if filter == "" {
+		return img
+	}
+
+	if b := img.Bounds(); b.Dx() == int(w) && b.Dy() == int(h) {
+		return img
+	}
+
+	return imaging.Resize(img, int(w), int(h), filters[filter])
+}
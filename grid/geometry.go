@@ -0,0 +1,294 @@
+package grid
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// Grid types.
+const (
+	TypeSquare    = "square"
+	TypeHex       = "hex"
+	TypeIsometric = "isometric"
+)
+
+// Hexagon orientations.
+const (
+	HexPointy = "pointy"
+	HexFlat   = "flat"
+)
+
+// Hexagon offset layouts: which rows (pointy)
+// or columns (flat) are shifted by half cell.
+const (
+	OffsetOdd  = "odd"
+	OffsetEven = "even"
+)
+
+// Isometric projections.
+const (
+	IsoDiamond   = "diamond"
+	IsoStaggered = "staggered"
+)
+
+// geometry computes the placement of the
+// grid cells in canvas coordinates.
+type geometry interface {
+	// extent returns the area covered by the cells.
+	extent(rows, cols int) (w, h float64)
+	// center returns the center of the cell.
+	center(row, col int) gg.Point
+	// corners returns the outline of the cell.
+	corners(row, col int) []gg.Point
+}
+
+// newGeometry returns the geometry of the grid type.
+func newGeometry(g *Grid) (geometry, error) {
+	size := float64(g.cellSize)
+
+	switch strings.ToLower(g.gridType) {
+	case "", TypeSquare:
+		return squareGeometry{w: float64(g.cellWidth), h: float64(g.cellHeight)}, nil
+	case TypeHex:
+		return newHexGeometry(size, g.hexOrientation, g.hexOffset)
+	case TypeIsometric:
+		return newIsoGeometry(size, g.rows, g.isoProjection, g.isoRatio)
+	default:
+		return nil, fmt.Errorf("unsupported grid type: %s", g.gridType)
+	}
+}
+
+// newHexGeometry returns the geometry of an hexagonal grid.
+func newHexGeometry(size float64, orientation, offset string) (geometry, error) {
+	res := hexGeometry{size: size}
+	switch strings.ToLower(orientation) {
+	case "", HexPointy:
+	case HexFlat:
+		res.flat = true
+	default:
+		return nil, fmt.Errorf("invalid hex orientation: %s", orientation)
+	}
+
+	switch strings.ToLower(offset) {
+	case "", OffsetOdd:
+	case OffsetEven:
+		res.even = true
+	default:
+		return nil, fmt.Errorf("invalid hex offset: %s", offset)
+	}
+
+	return res, nil
+}
+
+// squareGeometry lays out square (or rectangular) cells side by side.
+type squareGeometry struct {
+	w, h float64
+}
+
+func (sg squareGeometry) extent(rows, cols int) (w, h float64) {
+	return float64(cols) * sg.w, float64(rows) * sg.h
+}
+
+func (sg squareGeometry) center(row, col int) gg.Point {
+	return gg.Point{
+		X: 0.5*sg.w + float64(col)*sg.w,
+		Y: 0.5*sg.h + float64(row)*sg.h,
+	}
+}
+
+func (sg squareGeometry) corners(row, col int) []gg.Point {
+	x, y := float64(col)*sg.w, float64(row)*sg.h
+	return []gg.Point{
+		{X: x, Y: y}, {X: x + sg.w, Y: y},
+		{X: x + sg.w, Y: y + sg.h}, {X: x, Y: y + sg.h},
+	}
+}
+
+// hexGeometry lays out hexagons in offset coordinates: the cell
+// size is the distance between two opposite sides, so a square
+// tile of the same size fits the hexagon width (pointy) or height (flat).
+type hexGeometry struct {
+	size float64
+	flat bool
+	even bool
+}
+
+// radius returns the distance between the center and a corner.
+func (hg hexGeometry) radius() float64 {
+	return hg.size / math.Sqrt(3)
+}
+
+// shifted returns true if the row (pointy) or the
+// column (flat) is shifted by half cell.
+func (hg hexGeometry) shifted(idx int) bool {
+	return (idx%2 == 1) != hg.even
+}
+
+func (hg hexGeometry) extent(rows, cols int) (w, h float64) {
+	// the length of the side along which cells are staggered
+	stagger := func(n int) float64 {
+		return 2*hg.radius() + float64(n-1)*1.5*hg.radius()
+	}
+
+	// the length of the side along which cells are shifted
+	shift := func(n, other int) float64 {
+		res := float64(n) * hg.size
+		if other > 1 || hg.even {
+			res += 0.5 * hg.size
+		}
+		return res
+	}
+
+	if hg.flat {
+		return stagger(cols), shift(rows, cols)
+	}
+
+	return shift(cols, rows), stagger(rows)
+}
+
+func (hg hexGeometry) center(row, col int) gg.Point {
+	r := hg.radius()
+
+	if hg.flat {
+		y := 0.5*hg.size + float64(row)*hg.size
+		if hg.shifted(col) {
+			y += 0.5 * hg.size
+		}
+		return gg.Point{X: r + float64(col)*1.5*r, Y: y}
+	}
+
+	x := 0.5*hg.size + float64(col)*hg.size
+	if hg.shifted(row) {
+		x += 0.5 * hg.size
+	}
+	return gg.Point{X: x, Y: r + float64(row)*1.5*r}
+}
+
+func (hg hexGeometry) corners(row, col int) []gg.Point {
+	c, r := hg.center(row, col), hg.radius()
+
+	start := -30.0
+	if hg.flat {
+		start = 0
+	}
+
+	res := make([]gg.Point, 6)
+	for i := range res {
+		a := gg.Radians(start + 60*float64(i))
+		res[i] = gg.Point{X: c.X + r*math.Cos(a), Y: c.Y + r*math.Sin(a)}
+	}
+
+	return res
+}
+
+// isoGeometry projects the cells as diamonds as wide as the
+// cell size and tall as the cell size times the ratio; diamond maps
+// are rotated by 45 degrees, staggered maps shift the odd rows by
+// half cell. The area above the back cells leaves room for tiles
+// as tall as they are wide.
+type isoGeometry struct {
+	w, h      float64
+	rows      int
+	staggered bool
+}
+
+// newIsoGeometry returns the geometry of an isometric grid.
+func newIsoGeometry(size float64, rows int, projection string, ratio float64) (geometry, error) {
+	if ratio == 0 {
+		ratio = 0.5
+	}
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("invalid isometric ratio: %v, must be between 0 and 1", ratio)
+	}
+
+	res := isoGeometry{w: size, h: size * ratio, rows: rows}
+	switch strings.ToLower(projection) {
+	case "", IsoDiamond:
+	case IsoStaggered:
+		res.staggered = true
+	default:
+		return nil, fmt.Errorf("invalid isometric projection: %s", projection)
+	}
+
+	return res, nil
+}
+
+// headroom returns the space above the back cells.
+func (ig isoGeometry) headroom() float64 {
+	return ig.w - ig.h
+}
+
+func (ig isoGeometry) extent(rows, cols int) (w, h float64) {
+	if ig.staggered {
+		w = float64(cols) * ig.w
+		if rows > 1 {
+			w += 0.5 * ig.w
+		}
+		return w, ig.headroom() + 0.5*ig.h*float64(rows+1)
+	}
+
+	n := float64(rows + cols)
+	return 0.5 * ig.w * n, ig.headroom() + 0.5*ig.h*n
+}
+
+func (ig isoGeometry) center(row, col int) gg.Point {
+	if ig.staggered {
+		x := 0.5*ig.w + float64(col)*ig.w
+		if row%2 == 1 {
+			x += 0.5 * ig.w
+		}
+		return gg.Point{X: x, Y: ig.headroom() + 0.5*ig.h*float64(row+1)}
+	}
+
+	return gg.Point{
+		X: 0.5 * ig.w * float64(ig.rows+col-row),
+		Y: ig.headroom() + 0.5*ig.h*float64(row+col+1),
+	}
+}
+
+func (ig isoGeometry) corners(row, col int) []gg.Point {
+	c := ig.center(row, col)
+	return []gg.Point{
+		{X: c.X, Y: c.Y - 0.5*ig.h}, {X: c.X + 0.5*ig.w, Y: c.Y},
+		{X: c.X, Y: c.Y + 0.5*ig.h}, {X: c.X - 0.5*ig.w, Y: c.Y},
+	}
+}
+
+// edges returns the sides of all the cells: the ones shared by two
+// cells (inner) and the ones belonging to a single cell (outer).
+func edges(geom geometry, rows, cols int) (inner, outer [][2]gg.Point) {
+	type key struct{ x, y int64 }
+
+	count := make(map[key]int)
+	list := make(map[key][2]gg.Point)
+	order := []key{}
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			pts := geom.corners(r, c)
+			for i := range pts {
+				a, b := pts[i], pts[(i+1)%len(pts)]
+				// the rounded midpoint identifies the side
+				k := key{int64(math.Round(a.X + b.X)), int64(math.Round(a.Y + b.Y))}
+				if _, ok := list[k]; !ok {
+					list[k] = [2]gg.Point{a, b}
+					order = append(order, k)
+				}
+				count[k]++
+			}
+		}
+	}
+
+	for _, k := range order {
+		if count[k] > 1 {
+			inner = append(inner, list[k])
+		} else {
+			outer = append(outer, list[k])
+		}
+	}
+
+	return inner, outer
+}
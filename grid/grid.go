@@ -3,10 +3,12 @@ package grid
 import (
 	"fmt"
 	"image"
-	"image/png"
+	"image/color"
+	"image/draw"
 	"io"
 	"math"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/disintegration/imaging"
@@ -18,6 +20,8 @@ import (
 // Grid represents the grid structure
 type Grid struct {
 	cellSize          int
+	cellWidth         int
+	cellHeight        int
 	rows              int
 	cols              int
 	margin            int
@@ -30,6 +34,14 @@ type Grid struct {
 	backgroundColor   string
 
 	watermark string
+	format    string
+
+	gridType       string
+	hexOrientation string
+	hexOffset      string
+	isoProjection  string
+	isoRatio       float64
+	geom           geometry
 
 	canvasWidth  int
 	canvasHeight int
@@ -37,8 +49,9 @@ type Grid struct {
 	imageWidth  int
 	imageHeight int
 
-	font *truetype.Font
-	ctx  *gg.Context
+	font   *truetype.Font
+	canvas Canvas
+	ruler  *gg.Context
 }
 
 // NewGrid creates a new grid and sets it up with its configuration
@@ -59,19 +72,29 @@ func NewGrid(rows, cols int, cellSize int, opts ...func(*Grid)) (*Grid, error) {
 	res := Grid{
 		rows: rows, cols: cols,
 		cellSize:        cellSize,
+		cellWidth:       cellSize,
+		cellHeight:      cellSize,
 		margin:          24,
 		lineColor:       "#b8b8a7",
 		backgroundColor: "#ffffff",
-		borderColor:     "#161615",
+		format:          FormatPNG,
+		gridType:        TypeSquare,
 		font:            font,
+		ruler:           gg.NewContext(1, 1),
 	}
 
 	for _, opt := range opts {
 		opt(&res)
 	}
 
-	res.canvasWidth = res.cellSize * res.cols
-	res.canvasHeight = res.cellSize * res.rows
+	res.geom, err = newGeometry(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := res.geom.extent(res.rows, res.cols)
+	res.canvasWidth = int(math.Ceil(w))
+	res.canvasHeight = int(math.Ceil(h))
 	res.imageWidth = res.canvasWidth + 2*res.margin
 	res.imageHeight = res.canvasHeight + 2*res.margin
 
@@ -79,34 +102,57 @@ func NewGrid(rows, cols int, cellSize int, opts ...func(*Grid)) (*Grid, error) {
 	if max < res.imageHeight {
 		max = res.imageHeight
 	}
-	res.borderColor = "#ffffff00"
-	res.borderStrokeWidth = 0.002 * float64(max)
-	res.lineStrokeWidth = 0.001 * float64(max)
+	// the border is not visible unless a color is specified
+	if res.borderColor == "" {
+		res.borderColor = "#ffffff00"
+	}
+	if res.borderStrokeWidth <= 0 {
+		res.borderStrokeWidth = 0.002 * float64(max)
+	}
+	if res.lineStrokeWidth <= 0 {
+		res.lineStrokeWidth = 0.001 * float64(max)
+	}
 
-	res.ctx = gg.NewContext(res.imageWidth, res.imageHeight)
-	res.ctx.Translate(float64(res.margin), float64(res.margin))
-	res.ctx.SetHexColor(res.backgroundColor)
-	res.ctx.Clear()
+	res.canvas, err = newCanvas(res.format, res.imageWidth, res.imageHeight, res.margin)
+	if err != nil {
+		return nil, err
+	}
+	res.canvas.Clear(res.backgroundColor)
 
 	return &res, nil
 }
 
-// Context returns the grid drawing context
+// Context returns the grid drawing context;
+// it is nil if the grid is not rendered as PNG.
 func (g *Grid) Context() *gg.Context {
-	return g.ctx
+	if rc, ok := g.canvas.(*rasterCanvas); ok {
+		return rc.ctx
+	}
+	return nil
+}
+
+// Canvas returns the grid drawing backend.
+func (g *Grid) Canvas() Canvas {
+	return g.canvas
+}
+
+// Format returns the grid output format.
+func (g *Grid) Format() string {
+	return g.format
+}
+
+// Encode encodes the final image using the grid output format.
+func (g *Grid) Encode(w io.Writer) error {
+	return g.canvas.Encode(w)
 }
 
 // EncodePNG encodes the final image as PNG
 func (g *Grid) EncodePNG(w io.Writer) error {
-	// specify compression level
-	enc := png.Encoder{
-		CompressionLevel: png.BestSpeed,
-	}
-	if err := enc.Encode(w, g.ctx.Image()); err != nil {
-		return err
+	if _, ok := g.canvas.(*rasterCanvas); !ok {
+		return fmt.Errorf("cannot encode a %s grid as PNG", g.format)
 	}
 
-	return nil
+	return g.canvas.Encode(w)
 }
 
 // SavePNG saves the grid as PNG image.
@@ -128,147 +174,168 @@ func (g *Grid) SavePNG(filename string) error {
 
 // DrawBorder draws a border around the grid.
 func (g *Grid) DrawBorder() {
-	canvasWidth := float64(g.cellSize * g.cols)
-	canvasHeight := float64(g.cellSize * g.rows)
-
-	g.ctx.Push()
-	g.ctx.MoveTo(0, 0)
-	g.ctx.LineTo(0, canvasHeight)
-	g.ctx.LineTo(canvasWidth, canvasHeight)
-	g.ctx.LineTo(canvasWidth, 0)
-	g.ctx.LineTo(0, 0)
-
-	if g.borderDashes > 0 {
-		g.ctx.SetDash(g.borderDashes)
-	} else {
-		g.ctx.SetDash()
+	st := Style{
+		Stroke:      g.borderColor,
+		StrokeWidth: g.borderStrokeWidth,
+		Dashes:      g.borderDashes,
 	}
 
-	g.ctx.SetLineWidth(g.borderStrokeWidth)
-	g.ctx.SetHexColor(g.borderColor)
-	g.ctx.Stroke()
-	g.ctx.Pop()
-}
-
-// DrawWatermark draws the watermark.
-func (g *Grid) DrawWatermark() {
-	const lineSpacing = 1.2
-
-	w, h := float64(g.ctx.Width()), float64(g.ctx.Height())
+	if _, ok := g.geom.(squareGeometry); !ok {
+		_, outer := edges(g.geom, g.rows, g.cols)
+		for _, el := range outer {
+			g.canvas.DrawPath(el[:], false, st)
+		}
+		return
+	}
 
-	fontSize := 0.15 * math.Min(w, h)
-	face := truetype.NewFace(g.font, &truetype.Options{Size: fontSize})
+	canvasWidth := float64(g.cellWidth * g.cols)
+	canvasHeight := float64(g.cellHeight * g.rows)
 
-	g.ctx.Push()
-	g.ctx.SetHexColor("#c0c0c088")
-	g.ctx.SetFontFace(face)
+	pts := []gg.Point{
+		{X: 0, Y: 0},
+		{X: 0, Y: canvasHeight},
+		{X: canvasWidth, Y: canvasHeight},
+		{X: canvasWidth, Y: 0},
+		{X: 0, Y: 0},
+	}
 
-	s := math.Min(g.ctx.MeasureMultilineString(g.watermark, lineSpacing))
-	x := 0.5 * (w - 2*float64(g.margin))
-	y := 0.5 * (h - 2*float64(g.margin))
-	g.ctx.DrawStringWrapped(g.watermark, x, y, 0.5, 0.5, s, lineSpacing, gg.AlignCenter)
-	g.ctx.Pop()
+	g.canvas.DrawPath(pts, false, st)
 }
 
 // DrawGrid draws the grid.
 func (g *Grid) DrawGrid() {
-	g.ctx.Push()
-	for i := 1; i < g.cols; i++ {
-		x := float64(i * g.cellSize)
-		g.ctx.MoveTo(x, 0)
-		g.ctx.LineTo(x, float64(g.canvasHeight))
+	st := Style{
+		Stroke:      g.lineColor,
+		StrokeWidth: g.lineStrokeWidth,
+		Dashes:      g.lineDashes,
 	}
 
-	for i := 1; i < g.rows; i++ {
-		y := float64(i * g.cellSize)
-		g.ctx.MoveTo(0, y)
-		g.ctx.LineTo(float64(g.canvasWidth), y)
+	if _, ok := g.geom.(squareGeometry); !ok {
+		inner, _ := edges(g.geom, g.rows, g.cols)
+		for _, el := range inner {
+			g.canvas.DrawPath(el[:], false, st)
+		}
+		return
 	}
 
-	if g.lineDashes > 0 {
-		g.ctx.SetDash(g.lineDashes)
-	} else {
-		g.ctx.SetDash()
-	}
-	if g.lineColor != "" {
-		g.ctx.SetHexColor(g.lineColor)
+	for i := 1; i < g.cols; i++ {
+		x := float64(i * g.cellWidth)
+		g.canvas.DrawPath([]gg.Point{{X: x, Y: 0}, {X: x, Y: float64(g.canvasHeight)}}, false, st)
 	}
 
-	g.ctx.SetLineWidth(g.lineStrokeWidth)
-	g.ctx.Stroke()
-	g.ctx.Pop()
+	for i := 1; i < g.rows; i++ {
+		y := float64(i * g.cellHeight)
+		g.canvas.DrawPath([]gg.Point{{X: 0, Y: y}, {X: float64(g.canvasWidth), Y: y}}, false, st)
+	}
 }
 
 // DrawImage draws the image at row and col.
 // If the image size is greater then the gtid cell size
-// it will be shrinked.
-func (g *Grid) DrawImage(img image.Image, row, col int) error {
+// (or the area covered by the span) it will be shrinked,
+// unless a different fit mode is specified.
+// Flips are applied before rotation.
+func (g *Grid) DrawImage(img image.Image, row, col int, opts ...func(*DrawOptions)) error {
+	do := DrawOptions{opacity: 1, rows: 1, cols: 1}
+	for _, opt := range opts {
+		opt(&do)
+	}
+
 	if err := g.VerifyInBounds(row, col); err != nil {
 		return err
 	}
 
-	b := img.Bounds()
-	size := b.Max.X
-	if b.Max.Y > size {
-		size = b.Max.Y
+	if err := g.VerifyInBounds(row+do.rows-1, col+do.cols-1); err != nil {
+		return err
+	}
+
+	if do.rotation%90 != 0 {
+		return fmt.Errorf("invalid rotation angle %d, must be a multiple of 90", do.rotation)
+	}
+
+	img = transform(img, do.rotation, do.flipH, do.flipV)
+
+	if do.opacity < 1 {
+		img = fade(img, do.opacity)
+	}
+
+	aw, ah := float64(do.cols*g.cellWidth), float64(do.rows*g.cellHeight)
+	// isometric tiles can be as tall as the footprint is wide
+	ig, iso := g.geom.(isoGeometry)
+	if iso {
+		aw = 0.5 * float64(do.rows+do.cols) * ig.w
+		ah = aw
 	}
 
-	if g.cellSize < size {
-		img = imaging.Resize(img, g.cellSize, g.cellSize, imaging.Lanczos)
+	img, w, h, err := fit(img, aw, ah, do.fit, do.filter)
+	if err != nil {
+		return err
+	}
+
+	// the span center is halfway between the first and the last cell
+	first, last := g.CellCenter(row, col), g.CellCenter(row+do.rows-1, col+do.cols-1)
+	center := gg.Point{
+		X: 0.5*(first.X+last.X) + float64(do.offsetX),
+		Y: 0.5*(first.Y+last.Y) + float64(do.offsetY),
 	}
 
-	center := g.CellCenter(row, col)
+	// isometric tiles stand on the bottom corner of the cell
+	if iso {
+		bottom := last.Y + 0.5*ig.h + float64(do.offsetY)
+		g.canvas.DrawImage(img, float64(int(center.X)-int(0.5*w)), float64(int(bottom)-int(h)), w, h)
+		return nil
+	}
 
-	dc := g.Context()
-	dc.Push()
-	//g.ctx.RotateAbout(gg.Radians(alpha), center.X, center.Y)
-	dc.DrawImageAnchored(img, int(center.X), int(center.Y), 0.5, 0.5)
-	dc.Pop()
+	g.canvas.DrawImage(img, float64(int(center.X)-int(0.5*w)), float64(int(center.Y)-int(0.5*h)), w, h)
 
 	return nil
 }
 
 // DrawCoords draws all cells locations
 func (g *Grid) DrawCoords() {
-	cs := g.CellSize()
-	fontSize := 0.3 * cs
-
-	face := truetype.NewFace(g.font, &truetype.Options{Size: fontSize})
+	ts := TextStyle{
+		Font:  g.font,
+		Size:  0.3 * g.CellSize(),
+		Color: "#00000099",
+	}
 
-	g.ctx.Push()
-	g.ctx.SetFontFace(face)
-	g.ctx.SetHexColor("#00000099")
 	for i := 0; i < g.rows; i++ {
 		for j := 0; j < g.cols; j++ {
 			txt := fmt.Sprintf("%d,%d", i, j)
 			center := g.CellCenter(i, j)
-			sw, sh := g.ctx.MeasureString(txt)
+			sw, sh := g.measure(txt, ts)
 
-			g.ctx.Push()
-			g.ctx.SetHexColor("#00000022")
-			g.ctx.DrawRoundedRectangle(center.X-0.5*sw-4, center.Y-0.5*sh-4, sw+8, sh+8, 4)
-			g.ctx.Fill()
-			g.ctx.Pop()
+			g.canvas.DrawRoundedRect(center.X-0.5*sw-4, center.Y-0.5*sh-4, sw+8, sh+8, 4,
+				Style{Fill: "#00000022"})
 
-			g.ctx.DrawStringAnchored(txt, center.X, center.Y, 0.5, 0.35)
+			g.canvas.DrawString(txt, center.X, center.Y, 0.5, 0.35, ts)
 		}
 	}
-	g.ctx.Pop()
 }
 
 // CellSize returns the cell dimension
+// (the smaller side for rectangular cells).
 func (g *Grid) CellSize() float64 {
 	return float64(g.cellSize)
 }
 
+// CellWidth returns the cell width.
+func (g *Grid) CellWidth() float64 {
+	return float64(g.cellWidth)
+}
+
+// CellHeight returns the cell height.
+func (g *Grid) CellHeight() float64 {
+	return float64(g.cellHeight)
+}
+
 // CellCenter retuns the cell coordinates in the grid
 func (g *Grid) CellCenter(row, col int) gg.Point {
-	size := g.CellSize()
-
-	x := 0.5*size + float64(col)*size
-	y := 0.5*size + float64(row)*size
+	return g.geom.center(row, col)
+}
 
-	return gg.Point{X: x, Y: y}
+// CellCorners returns the outline of the cell.
+func (g *Grid) CellCorners(row, col int) []gg.Point {
+	return g.geom.corners(row, col)
 }
 
 // VerifyInBounds verify that the coordinates
@@ -280,6 +347,74 @@ func (g *Grid) VerifyInBounds(row, col int) error {
 	return nil
 }
 
+// CellRect makes the grid cells rectangular (square grids only);
+// it overrides the cell size specified creating the grid.
+func CellRect(width, height int) func(*Grid) {
+	return func(g *Grid) {
+		if width <= 0 || height <= 0 {
+			return
+		}
+		g.cellWidth, g.cellHeight = width, height
+		g.cellSize = width
+		if height < width {
+			g.cellSize = height
+		}
+	}
+}
+
+// Hex makes the grid of hexagons with the specified orientation
+// (pointy or flat) and offset layout (odd or even rows, for pointy
+// hexagons, or columns, for flat ones, are shifted by half cell).
+func Hex(orientation, offset string) func(*Grid) {
+	return func(g *Grid) {
+		g.gridType = TypeHex
+		g.hexOrientation = orientation
+		g.hexOffset = offset
+	}
+}
+
+// Isometric projects the grid cells as diamonds (diamond or staggered
+// projection); the ratio is the diamond height to width ratio (0.5 if zero).
+// Images are bottom aligned to the cell, so taller tiles overlap the
+// cells behind them: draw the cells in back to front (row by row) order.
+func Isometric(projection string, ratio float64) func(*Grid) {
+	return func(g *Grid) {
+		g.gridType = TypeIsometric
+		g.isoProjection = projection
+		g.isoRatio = ratio
+	}
+}
+
+// Isometric returns true if the grid cells are isometric diamonds.
+func (g *Grid) Isometric() bool {
+	_, ok := g.geom.(isoGeometry)
+	return ok
+}
+
+// Lines sets the style of the lines drawn by DrawGrid; empty
+// color and zero width keep the defaults, zero dashes draw solid lines.
+func Lines(color string, width, dashes float64) func(*Grid) {
+	return func(g *Grid) {
+		if color != "" {
+			g.lineColor = color
+		}
+		g.lineStrokeWidth = width
+		g.lineDashes = dashes
+	}
+}
+
+// Border sets the style of the border drawn by DrawBorder; empty
+// color and zero width keep the defaults, zero dashes draw solid lines.
+func Border(color string, width, dashes float64) func(*Grid) {
+	return func(g *Grid) {
+		if color != "" {
+			g.borderColor = color
+		}
+		g.borderStrokeWidth = width
+		g.borderDashes = dashes
+	}
+}
+
 // Background sets the grid background color
 func Background(hex string) func(*Grid) {
 	return func(g *Grid) {
@@ -300,3 +435,118 @@ func Watermark(text string) func(*Grid) {
 		g.watermark = text
 	}
 }
+
+// Font sets the font used to draw
+// labels, coordinates and watermark.
+func Font(f *truetype.Font) func(*Grid) {
+	return func(g *Grid) {
+		if f != nil {
+			g.font = f
+		}
+	}
+}
+
+// Format sets the grid output format (png or svg).
+func Format(name string) func(*Grid) {
+	return func(g *Grid) {
+		g.format = strings.ToLower(name)
+	}
+}
+
+// DrawOptions holds the settings used
+// to draw a single image in a grid cell.
+type DrawOptions struct {
+	opacity  float64
+	offsetX  int
+	offsetY  int
+	rotation int
+	flipH    bool
+	flipV    bool
+	rows     int
+	cols     int
+	fit      string
+	filter   string
+}
+
+// Opacity sets the image opacity (from 0 to 1).
+func Opacity(val float64) func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		do.opacity = math.Max(0, math.Min(1, val))
+	}
+}
+
+// Offset shifts the image by the specified amount of pixels.
+func Offset(x, y int) func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		do.offsetX = x
+		do.offsetY = y
+	}
+}
+
+// Span makes the image occupy the specified number of cells,
+// starting from the target cell towards the bottom right.
+func Span(rows, cols int) func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		if rows > 0 {
+			do.rows = rows
+		}
+		if cols > 0 {
+			do.cols = cols
+		}
+	}
+}
+
+// Rotate rotates the image clockwise by the specified
+// angle in degrees; only multiples of 90 are allowed.
+func Rotate(deg int) func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		do.rotation = ((deg % 360) + 360) % 360
+	}
+}
+
+// FlipH flips the image horizontally.
+func FlipH() func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		do.flipH = true
+	}
+}
+
+// FlipV flips the image vertically.
+func FlipV() func(*DrawOptions) {
+	return func(do *DrawOptions) {
+		do.flipV = true
+	}
+}
+
+// transform returns the image flipped and
+// then rotated clockwise by the specified angle.
+func transform(img image.Image, rotation int, flipH, flipV bool) image.Image {
+	if flipH {
+		img = imaging.FlipH(img)
+	}
+	if flipV {
+		img = imaging.FlipV(img)
+	}
+
+	// imaging rotates counter-clockwise
+	switch rotation {
+	case 90:
+		img = imaging.Rotate270(img)
+	case 180:
+		img = imaging.Rotate180(img)
+	case 270:
+		img = imaging.Rotate90(img)
+	}
+
+	return img
+}
+
+// fade returns a copy of the image
+// with the alpha channel scaled by opacity.
+func fade(img image.Image, opacity float64) image.Image {
+	b := img.Bounds()
+	res := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	mask := image.NewUniform(color.Alpha{A: uint8(opacity * 255)})
+	draw.DrawMask(res, res.Bounds(), img, b.Min, mask, image.Point{}, draw.Over)
+	return res
+}
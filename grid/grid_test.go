@@ -3,6 +3,9 @@ package grid
 import (
 	"bytes"
 	"encoding/base64"
+	"image"
+	"image/color"
+	"math"
 	"strings"
 	"testing"
 
@@ -97,3 +100,476 @@ func TestGridLayout(t *testing.T) {
 	//t.Logf(str)
 	assert.True(t, strings.HasPrefix(str, "iVBORw0KGgoAAAANSUhEUgAAAGAAAABgCAIAAABt+uBvAAAErElEQVR4Aeyb3U7qShiG"))
 }
+
+func TestTransform(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.Black)
+
+	tests := []struct {
+		rotation     int
+		flipH, flipV bool
+		black        image.Point
+	}{
+		{0, false, false, image.Pt(0, 0)},
+		{90, false, false, image.Pt(0, 0)},
+		{180, false, false, image.Pt(1, 0)},
+		{270, false, false, image.Pt(0, 1)},
+		{0, true, false, image.Pt(1, 0)},
+		{90, true, false, image.Pt(0, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			img := transform(src, tt.rotation, tt.flipH, tt.flipV)
+			_, _, _, a := img.At(tt.black.X, tt.black.Y).RGBA()
+			if a == 0 {
+				t.Errorf("expected opaque pixel at %v", tt.black)
+			}
+		})
+	}
+}
+
+func TestGridSVG(t *testing.T) {
+	grid, err := NewGrid(2, 2, 8, Format(FormatSVG))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for _, cell := range []image.Point{{0, 0}, {1, 1}} {
+		if err := grid.DrawImage(img, cell.X, cell.Y); err != nil {
+			t.Fatal(err)
+		}
+	}
+	grid.DrawBorder()
+
+	var data bytes.Buffer
+	if err := grid.Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	str := data.String()
+	assert.True(t, strings.HasPrefix(str, "<svg "))
+	assert.Equal(t, 1, strings.Count(str, "<symbol "))
+	assert.Equal(t, 2, strings.Count(str, "<use "))
+	assert.Error(t, grid.EncodePNG(&data))
+}
+
+func TestSVGImageError(t *testing.T) {
+	sc := newSVGCanvas(8, 8, 0)
+
+	// an empty image can not be encoded as PNG
+	sc.DrawImage(image.NewNRGBA(image.Rect(0, 0, 0, 0)), 0, 0, 8, 8)
+
+	assert.Error(t, sc.Encode(&bytes.Buffer{}))
+}
+
+func TestGridHex(t *testing.T) {
+	tests := []struct {
+		orientation, offset string
+		width, height       int
+		cell                gg.Point
+	}{
+		// r = 64/sqrt(3) ~ 36.95
+		{HexPointy, OffsetOdd, 3*64 + 32, 185, gg.Point{X: 96, Y: 36.95}},
+		{HexPointy, OffsetEven, 3*64 + 32, 185, gg.Point{X: 128, Y: 36.95}},
+		{HexFlat, OffsetOdd, 185, 3*64 + 32, gg.Point{X: 92.38, Y: 64}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.orientation+tt.offset, func(t *testing.T) {
+			gr, err := NewGrid(3, 3, 64, Hex(tt.orientation, tt.offset), Margin(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gr.canvasWidth != tt.width || gr.canvasHeight != tt.height {
+				t.Errorf("got [%dx%d] want [%dx%d]", gr.canvasWidth, gr.canvasHeight, tt.width, tt.height)
+			}
+
+			got := gr.CellCenter(0, 1)
+			if math.Abs(got.X-tt.cell.X) > 0.01 || math.Abs(got.Y-tt.cell.Y) > 0.01 {
+				t.Errorf("got [%v] want [%v]", got, tt.cell)
+			}
+		})
+	}
+
+	if _, err := NewGrid(3, 3, 64, Hex("round", OffsetOdd)); err == nil {
+		t.Errorf("expected an error for an invalid orientation")
+	}
+}
+
+func TestEdges(t *testing.T) {
+	gr, err := NewGrid(2, 2, 64, Hex(HexPointy, OffsetOdd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inner, outer := edges(gr.geom, 2, 2)
+	if len(inner) != 5 || len(outer) != 14 {
+		t.Errorf("got [%d, %d] want [5, 14]", len(inner), len(outer))
+	}
+
+	inner, outer = edges(squareGeometry{w: 64, h: 64}, 2, 3)
+	if len(inner) != 7 || len(outer) != 10 {
+		t.Errorf("got [%d, %d] want [7, 10]", len(inner), len(outer))
+	}
+}
+
+func TestGridIsometric(t *testing.T) {
+	tests := []struct {
+		projection    string
+		rows, cols    int
+		width, height int
+		row, col      int
+		cell          gg.Point
+	}{
+		{IsoDiamond, 2, 3, 160, 112, 0, 0, gg.Point{X: 64, Y: 48}},
+		{IsoDiamond, 2, 3, 160, 112, 1, 2, gg.Point{X: 96, Y: 96}},
+		{IsoStaggered, 3, 2, 160, 96, 1, 0, gg.Point{X: 64, Y: 64}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.projection, func(t *testing.T) {
+			gr, err := NewGrid(tt.rows, tt.cols, 64, Isometric(tt.projection, 0.5), Margin(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gr.canvasWidth != tt.width || gr.canvasHeight != tt.height {
+				t.Errorf("got [%dx%d] want [%dx%d]", gr.canvasWidth, gr.canvasHeight, tt.width, tt.height)
+			}
+
+			if got := gr.CellCenter(tt.row, tt.col); got != tt.cell {
+				t.Errorf("got [%v] want [%v]", got, tt.cell)
+			}
+		})
+	}
+
+	if _, err := NewGrid(3, 3, 64, Isometric(IsoDiamond, 2)); err == nil {
+		t.Errorf("expected an error for an invalid ratio")
+	}
+}
+
+func TestDrawSubImage(t *testing.T) {
+	atlas := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for x := 8; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			atlas.Set(x, y, color.Black)
+		}
+	}
+
+	gr, err := NewGrid(1, 1, 8, Margin(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drawn at its native size, the tile must not be shifted by its origin
+	if err := gr.DrawImage(atlas.SubImage(image.Rect(8, 0, 16, 8)), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if r, _, _, _ := gr.Context().Image().At(4, 4).RGBA(); r != 0 {
+		t.Errorf("got [%v] want [%v]", r, 0)
+	}
+}
+
+func TestDrawSubImageSmallerThanCell(t *testing.T) {
+	// a 16x8 atlas: white tile on the left, black tile on the right
+	atlas := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 8; y++ {
+			if x < 8 {
+				atlas.Set(x, y, color.White)
+			} else {
+				atlas.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	gr, err := NewGrid(1, 1, 32, Margin(0), Background("#ff0000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the 8x8 tile is not resized and is centered in the 32x32 cell
+	if err := gr.DrawImage(atlas.SubImage(image.Rect(8, 0, 16, 8)), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	out := gr.Context().Image()
+	tests := []struct {
+		x, y    int
+		r, g, b uint32
+	}{
+		{12, 12, 0, 0, 0},
+		{19, 19, 0, 0, 0},
+		{11, 16, 0xffff, 0, 0},
+		{20, 16, 0xffff, 0, 0},
+		{4, 4, 0xffff, 0, 0},
+	}
+
+	for _, tt := range tests {
+		r, g, b, _ := out.At(tt.x, tt.y).RGBA()
+		if r != tt.r || g != tt.g || b != tt.b {
+			t.Errorf("(%d, %d): got [%v %v %v] want [%v %v %v]", tt.x, tt.y, r, g, b, tt.r, tt.g, tt.b)
+		}
+	}
+}
+
+func TestGridCellRect(t *testing.T) {
+	gr, err := NewGrid(2, 3, 64, CellRect(32, 8), Margin(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gr.canvasWidth != 96 || gr.canvasHeight != 16 {
+		t.Errorf("got [%dx%d] want [96x16]", gr.canvasWidth, gr.canvasHeight)
+	}
+
+	if got, want := gr.CellCenter(1, 2), (gg.Point{X: 80, Y: 12}); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+
+	// a square image is shrinked to 8x8 in the middle of the cell
+	sq := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			sq.Set(x, y, color.Black)
+		}
+	}
+	if err := gr.DrawImage(sq, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	out := gr.Context().Image()
+	if r, _, _, _ := out.At(16, 4).RGBA(); r != 0 {
+		t.Errorf("got [%v] want [%v]", r, 0)
+	}
+	if r, _, _, _ := out.At(2, 4).RGBA(); r == 0 {
+		t.Errorf("expected the image to keep its aspect ratio")
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h   int
+		mode   string
+		filter string
+		want   image.Point
+	}{
+		{16, 16, "", "", image.Pt(16, 16)},
+		{128, 64, "", "", image.Pt(64, 32)},
+		{16, 16, FitContain, "", image.Pt(64, 64)},
+		{32, 16, FitCover, "", image.Pt(64, 64)},
+		{32, 16, FitStretch, "nearest", image.Pt(64, 64)},
+		{128, 64, FitNone, "", image.Pt(128, 64)},
+		{20, 20, FitUpscaleNearest, "", image.Pt(60, 60)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, tt.w, tt.h))
+
+			res, w, h, err := fit(img, 64, 64, tt.mode, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := image.Pt(int(w), int(h)); got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+
+			// with a filter the image is resized to the destination size
+			if tt.mode != "" && tt.mode != FitNone && tt.mode != FitContain {
+				if got := res.Bounds().Size(); got != tt.want {
+					t.Errorf("got [%v] want [%v]", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if err := CheckFit("zoom", ""); err == nil {
+		t.Errorf("expected an error for an invalid fit mode")
+	}
+	if err := CheckFit(FitContain, "bicubic"); err == nil {
+		t.Errorf("expected an error for an invalid filter")
+	}
+}
+
+func TestWatermarkAnchor(t *testing.T) {
+	gr, err := NewGrid(2, 2, 40, Margin(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		position string
+		want     []float64
+	}{
+		{PositionCenter, []float64{40, 40, 0.5, 0.5}},
+		{PositionTopLeft, []float64{-8, -8, 0, 0}},
+		{PositionBottomRight, []float64{88, 88, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.position, func(t *testing.T) {
+			x, y, ax, ay := gr.watermarkAnchor(tt.position)
+			for i, got := range []float64{x, y, ax, ay} {
+				if math.Abs(got-tt.want[i]) > 1e-9 {
+					t.Errorf("got [%v] want [%v]", []float64{x, y, ax, ay}, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWatermarkTextOpacity(t *testing.T) {
+	gr, err := NewGrid(2, 2, 40, Format(FormatSVG), Watermark("DRAFT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr.DrawWatermark(WatermarkColor("#ff0000"), WatermarkOpacity(0.2))
+
+	var data bytes.Buffer
+	if err := gr.Encode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Contains(t, data.String(), `fill="#ff0000" fill-opacity="0.2"`)
+}
+
+func TestRoute(t *testing.T) {
+	// a wall on column 1, open on the last row
+	wall := func(row, col int) bool {
+		return col == 1 && row < 2
+	}
+	none := func(int, int) bool { return false }
+
+	tests := []struct {
+		name    string
+		from    [2]int
+		to      [2]int
+		blocked func(row, col int) bool
+		want    [][2]int
+	}{
+		{"straight", [2]int{0, 0}, [2]int{0, 2}, none, [][2]int{{0, 0}, {0, 1}, {0, 2}}},
+		{"one turn", [2]int{0, 0}, [2]int{1, 1}, none, [][2]int{{0, 0}, {0, 1}, {1, 1}}},
+		{"around", [2]int{0, 0}, [2]int{0, 2}, wall,
+			[][2]int{{0, 0}, {1, 0}, {2, 0}, {2, 1}, {2, 2}, {1, 2}, {0, 2}}},
+		{"blocked end", [2]int{0, 0}, [2]int{0, 1}, wall, [][2]int{{0, 0}, {0, 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Route(3, 3, tt.from, tt.to, tt.blocked)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	if _, err := Route(3, 3, [2]int{0, 0}, [2]int{3, 0}, none); err == nil {
+		t.Errorf("expected an error for an out of bounds cell")
+	}
+}
+
+func TestDrawConnector(t *testing.T) {
+	gr, err := NewGrid(3, 3, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gr.DrawConnector([][2]int{{0, 0}}); err == nil {
+		t.Errorf("expected an error for a single cell path")
+	}
+	if err := gr.DrawConnector([][2]int{{0, 0}, {0, 3}}); err == nil {
+		t.Errorf("expected an error for an out of bounds cell")
+	}
+	if err := gr.DrawConnector([][2]int{{0, 0}, {0, 1}, {1, 1}}, Arrows(true, true), ConnectorLabel("x")); err != nil {
+		t.Error(err)
+	}
+
+	for _, opt := range []func(*Grid){Hex(HexPointy, OffsetOdd), Isometric(IsoDiamond, 0)} {
+		gr, err := NewGrid(3, 3, 32, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := gr.DrawConnector([][2]int{{0, 0}, {0, 1}}); err == nil {
+			t.Errorf("expected an error for a %s grid", gr.gridType)
+		}
+	}
+}
+
+func TestGroupRect(t *testing.T) {
+	gr, err := NewGrid(3, 4, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                       string
+		fromRow, fromCol           int
+		toRow, toCol               int
+		opts                       []func(*GroupOptions)
+		wantX, wantY, wantW, wantH float64
+	}{
+		{"cells", 1, 1, 2, 2, []func(*GroupOptions){GroupBorder("", 2, 0)}, 33, 33, 62, 62},
+		{"swapped", 2, 2, 1, 1, []func(*GroupOptions){GroupBorder("", 2, 0)}, 33, 33, 62, 62},
+		{"inset", 0, 0, 0, 3, []func(*GroupOptions){GroupBorder("", 2, 0), GroupInset(4)}, 5, 5, 118, 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gro := gr.groupOptions(tt.opts)
+			x, y, w, h, err := gr.groupRect(tt.fromRow, tt.fromCol, tt.toRow, tt.toCol, gro)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, []float64{tt.wantX, tt.wantY, tt.wantW, tt.wantH}, []float64{x, y, w, h})
+		})
+	}
+
+	if err := gr.DrawGroup(0, 0, 3, 0); err == nil {
+		t.Errorf("expected an error for an out of bounds cell")
+	}
+}
+
+func TestDrawLabelOuterCells(t *testing.T) {
+	tests := []struct {
+		anchor string
+		row    int
+	}{
+		{AnchorBelow, 1},
+		{AnchorAbove, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.anchor, func(t *testing.T) {
+			gr, err := NewGrid(2, 1, 64, Margin(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := gr.DrawLabel("a very long label", tt.row, 0, Anchor(tt.anchor)); err != nil {
+				t.Fatal(err)
+			}
+
+			// with no margin the label must be moved inside the image
+			out := gr.Context().Image()
+			dark := 0
+			for y := 0; y < out.Bounds().Dy(); y++ {
+				for x := 0; x < out.Bounds().Dx(); x++ {
+					if r, _, _, _ := out.At(x, y).RGBA(); r < 0x8000 {
+						dark++
+					}
+				}
+			}
+			if dark == 0 {
+				t.Errorf("the label is not visible")
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package grid
+
+import (
+	"math"
+)
+
+// GroupOptions holds the settings used to draw a group boundary.
+type GroupOptions struct {
+	title       string
+	borderColor string
+	borderWidth float64
+	dashes      float64
+	fill        string
+	radius      float64
+	inset       float64
+}
+
+// GroupTitle sets the text drawn at the top left corner of the group.
+func GroupTitle(text string) func(*GroupOptions) {
+	return func(gro *GroupOptions) {
+		gro.title = text
+	}
+}
+
+// GroupBorder sets the group border color, width and dash length
+// (zero values keep the defaults, zero dashes for a solid line).
+func GroupBorder(hex string, width, dashes float64) func(*GroupOptions) {
+	return func(gro *GroupOptions) {
+		if hex != "" {
+			gro.borderColor = hex
+		}
+		if width > 0 {
+			gro.borderWidth = width
+		}
+		gro.dashes = dashes
+	}
+}
+
+// GroupFill sets the group background color.
+func GroupFill(hex string) func(*GroupOptions) {
+	return func(gro *GroupOptions) {
+		gro.fill = hex
+	}
+}
+
+// GroupRadius sets the group corner radius.
+func GroupRadius(val float64) func(*GroupOptions) {
+	return func(gro *GroupOptions) {
+		if val > 0 {
+			gro.radius = val
+		}
+	}
+}
+
+// GroupInset moves the group boundary inside its cells by the
+// specified amount of pixels (i.e. to tell nested groups apart).
+func GroupInset(val float64) func(*GroupOptions) {
+	return func(gro *GroupOptions) {
+		if val > 0 {
+			gro.inset = val
+		}
+	}
+}
+
+// DrawGroup draws the boundary (background and border) around the
+// range of cells from the top left to the bottom right one (both
+// included); draw it before the tiles so that it stays beneath them.
+func (g *Grid) DrawGroup(fromRow, fromCol, toRow, toCol int, opts ...func(*GroupOptions)) error {
+	gro := g.groupOptions(opts)
+
+	x, y, w, h, err := g.groupRect(fromRow, fromCol, toRow, toCol, gro)
+	if err != nil {
+		return err
+	}
+
+	g.canvas.DrawRoundedRect(x, y, w, h, gro.radius, Style{
+		Fill:        gro.fill,
+		Stroke:      gro.borderColor,
+		StrokeWidth: gro.borderWidth,
+		Dashes:      gro.dashes,
+	})
+
+	return nil
+}
+
+// DrawGroupTitle draws the group title at its top left corner;
+// draw it after the tiles so that it is not covered by them.
+func (g *Grid) DrawGroupTitle(fromRow, fromCol, toRow, toCol int, opts ...func(*GroupOptions)) error {
+	gro := g.groupOptions(opts)
+	if gro.title == "" {
+		return nil
+	}
+
+	x, y, _, _, err := g.groupRect(fromRow, fromCol, toRow, toCol, gro)
+	if err != nil {
+		return err
+	}
+
+	ts := TextStyle{
+		Font:  g.font,
+		Size:  0.15 * g.CellSize(),
+		Color: gro.borderColor,
+	}
+
+	sw, sh := g.measure(gro.title, ts)
+	pad := 0.25 * sh
+	x, y = x+math.Max(pad, 0.5*gro.radius), y+pad
+
+	g.canvas.DrawRoundedRect(x, y, sw+2*pad, sh+2*pad, pad, Style{Fill: g.backgroundColor})
+	g.canvas.DrawString(gro.title, x+pad, y+pad, 0, 1, ts)
+
+	return nil
+}
+
+// groupOptions returns the group settings with the defaults.
+func (g *Grid) groupOptions(opts []func(*GroupOptions)) GroupOptions {
+	gro := GroupOptions{
+		borderColor: "#161615",
+		borderWidth: math.Max(1, 0.02*g.CellSize()),
+	}
+	for _, opt := range opts {
+		opt(&gro)
+	}
+
+	return gro
+}
+
+// groupRect returns the bounds of the cells in the range,
+// reduced by the inset and by half of the border width.
+func (g *Grid) groupRect(fromRow, fromCol, toRow, toCol int, gro GroupOptions) (x, y, w, h float64, err error) {
+	if err := g.VerifyInBounds(fromRow, fromCol); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if err := g.VerifyInBounds(toRow, toCol); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if fromRow > toRow {
+		fromRow, toRow = toRow, fromRow
+	}
+	if fromCol > toCol {
+		fromCol, toCol = toCol, fromCol
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for r := fromRow; r <= toRow; r++ {
+		for c := fromCol; c <= toCol; c++ {
+			for _, pt := range g.CellCorners(r, c) {
+				minX, minY = math.Min(minX, pt.X), math.Min(minY, pt.Y)
+				maxX, maxY = math.Max(maxX, pt.X), math.Max(maxY, pt.Y)
+			}
+		}
+	}
+
+	d := gro.inset + 0.5*gro.borderWidth
+	return minX + d, minY + d, maxX - minX - 2*d, maxY - minY - 2*d, nil
+}
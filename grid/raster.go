@@ -0,0 +1,112 @@
+package grid
+
+import (
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+)
+
+// rasterCanvas draws the grid on a gg context.
+type rasterCanvas struct {
+	ctx *gg.Context
+}
+
+func newRasterCanvas(width, height, margin int) *rasterCanvas {
+	ctx := gg.NewContext(width, height)
+	ctx.Translate(float64(margin), float64(margin))
+	return &rasterCanvas{ctx: ctx}
+}
+
+// Clear fills the whole image with the color.
+func (rc *rasterCanvas) Clear(hex string) {
+	rc.ctx.SetHexColor(hex)
+	rc.ctx.Clear()
+}
+
+// DrawImage draws the image scaled to the specified rectangle.
+func (rc *rasterCanvas) DrawImage(img image.Image, x, y, w, h float64) {
+	if b := img.Bounds(); b.Dx() != int(w) || b.Dy() != int(h) {
+		img = imaging.Resize(img, int(w), int(h), imaging.Lanczos)
+	}
+
+	// sub images (i.e. tiles of an atlas) keep their
+	// origin, gg would draw them shifted by it
+	b := img.Bounds()
+	rc.ctx.DrawImage(img, int(x)-b.Min.X, int(y)-b.Min.Y)
+}
+
+// DrawPath fills and/or strokes the polyline.
+func (rc *rasterCanvas) DrawPath(pts []gg.Point, closed bool, st Style) {
+	if len(pts) == 0 {
+		return
+	}
+
+	rc.ctx.Push()
+	defer rc.ctx.Pop()
+
+	rc.ctx.MoveTo(pts[0].X, pts[0].Y)
+	for _, pt := range pts[1:] {
+		rc.ctx.LineTo(pt.X, pt.Y)
+	}
+	if closed {
+		rc.ctx.ClosePath()
+	}
+
+	rc.paint(st)
+}
+
+// DrawRoundedRect fills and/or strokes the rectangle.
+func (rc *rasterCanvas) DrawRoundedRect(x, y, w, h, r float64, st Style) {
+	rc.ctx.Push()
+	defer rc.ctx.Pop()
+
+	rc.ctx.DrawRoundedRectangle(x, y, w, h, r)
+	rc.paint(st)
+}
+
+// DrawString draws the text at the specified anchor point.
+func (rc *rasterCanvas) DrawString(s string, x, y, ax, ay float64, ts TextStyle) {
+	rc.ctx.Push()
+	defer rc.ctx.Pop()
+
+	rc.ctx.SetFontFace(truetype.NewFace(ts.Font, &truetype.Options{Size: ts.Size}))
+	rc.ctx.SetHexColor(ts.Color)
+	if ts.Angle != 0 {
+		rc.ctx.RotateAbout(gg.Radians(ts.Angle), x, y)
+	}
+	rc.ctx.DrawStringAnchored(s, x, y, ax, ay)
+}
+
+// Encode writes the image as PNG.
+func (rc *rasterCanvas) Encode(w io.Writer) error {
+	// specify compression level
+	enc := png.Encoder{
+		CompressionLevel: png.BestSpeed,
+	}
+	return enc.Encode(w, rc.ctx.Image())
+}
+
+// paint fills and strokes the current path.
+func (rc *rasterCanvas) paint(st Style) {
+	if st.Fill != "" {
+		rc.ctx.SetHexColor(st.Fill)
+		rc.ctx.FillPreserve()
+	}
+
+	if st.Stroke != "" && st.StrokeWidth > 0 {
+		if st.Dashes > 0 {
+			rc.ctx.SetDash(st.Dashes)
+		} else {
+			rc.ctx.SetDash()
+		}
+		rc.ctx.SetLineWidth(st.StrokeWidth)
+		rc.ctx.SetHexColor(st.Stroke)
+		rc.ctx.StrokePreserve()
+	}
+
+	rc.ctx.ClearPath()
+}
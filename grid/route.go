@@ -0,0 +1,109 @@
+package grid
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Route returns the shortest orthogonal path of cells, as [row, col]
+// pairs, from one cell to another that does not pass through the
+// blocked cells (the two ends excepted); among the shortest paths
+// the one with fewer turns is preferred. If every path is blocked,
+// the route ignores the blocked cells.
+func Route(rows, cols int, from, to [2]int, blocked func(row, col int) bool) ([][2]int, error) {
+	for _, el := range [][2]int{from, to} {
+		if el[0] < 0 || el[0] >= rows || el[1] < 0 || el[1] >= cols {
+			return nil, fmt.Errorf("cell (%d, %d) is out of bounds", el[0], el[1])
+		}
+	}
+
+	if res := route(rows, cols, from, to, blocked); res != nil {
+		return res, nil
+	}
+
+	return route(rows, cols, from, to, func(int, int) bool { return false }), nil
+}
+
+// moves are the orthogonal directions: N, E, S, W.
+var moves = [4][2]int{{-1, 0}, {0, 1}, {1, 0}, {0, -1}}
+
+// routeState is a cell reached moving along a direction.
+type routeState struct {
+	row, col, dir int
+}
+
+// route finds the path with Dijkstra: each step costs 2, each turn 1.
+func route(rows, cols int, from, to [2]int, blocked func(row, col int) bool) [][2]int {
+	const stepCost, turnCost = 2, 1
+
+	dist := map[routeState]int{}
+	prev := map[routeState]routeState{}
+
+	pq := &routeQueue{}
+	for d := range moves {
+		st := routeState{from[0], from[1], d}
+		dist[st] = 0
+		heap.Push(pq, routeItem{st, 0})
+	}
+
+	for pq.Len() > 0 {
+		it := heap.Pop(pq).(routeItem)
+		if it.cost > dist[it.state] {
+			continue
+		}
+
+		cur := it.state
+		if cur.row == to[0] && cur.col == to[1] {
+			res := [][2]int{{cur.row, cur.col}}
+			for cur.row != from[0] || cur.col != from[1] {
+				cur = prev[cur]
+				res = append([][2]int{{cur.row, cur.col}}, res...)
+			}
+			return res
+		}
+
+		for d, mv := range moves {
+			r, c := cur.row+mv[0], cur.col+mv[1]
+			if r < 0 || r >= rows || c < 0 || c >= cols {
+				continue
+			}
+			if (r != to[0] || c != to[1]) && blocked(r, c) {
+				continue
+			}
+
+			cost := it.cost + stepCost
+			if d != cur.dir && (cur.row != from[0] || cur.col != from[1]) {
+				cost += turnCost
+			}
+
+			next := routeState{r, c, d}
+			if old, ok := dist[next]; ok && old <= cost {
+				continue
+			}
+			dist[next] = cost
+			prev[next] = cur
+			heap.Push(pq, routeItem{next, cost})
+		}
+	}
+
+	return nil
+}
+
+type routeItem struct {
+	state routeState
+	cost  int
+}
+
+// routeQueue is a min-heap of route items.
+type routeQueue []routeItem
+
+func (q routeQueue) Len() int            { return len(q) }
+func (q routeQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q routeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x interface{}) { *q = append(*q, x.(routeItem)) }
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
@@ -0,0 +1,186 @@
+package grid
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+)
+
+// svgCanvas draws the grid as SVG elements.
+// Each distinct image is embedded once as a <symbol>
+// and then referenced by <use> elements.
+type svgCanvas struct {
+	width, height int
+	margin        int
+
+	defs    bytes.Buffer
+	body    bytes.Buffer
+	symbols map[string]bool
+
+	ruler *gg.Context
+
+	// err is the first error drawing the images,
+	// returned by Encode
+	err error
+}
+
+func newSVGCanvas(width, height, margin int) *svgCanvas {
+	return &svgCanvas{
+		width: width, height: height,
+		margin:  margin,
+		symbols: make(map[string]bool),
+		ruler:   gg.NewContext(1, 1),
+	}
+}
+
+// Clear fills the whole image with the color.
+func (sc *svgCanvas) Clear(hex string) {
+	sc.body.Reset()
+	fmt.Fprintf(&sc.body, `<rect x="%d" y="%d" width="%d" height="%d"%s/>`+"\n",
+		-sc.margin, -sc.margin, sc.width, sc.height, fillAttrs(hex))
+}
+
+// DrawImage references the embedded image scaled to the specified rectangle.
+func (sc *svgCanvas) DrawImage(img image.Image, x, y, w, h float64) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		if sc.err == nil {
+			sc.err = fmt.Errorf("svg image: %s", err)
+		}
+		return
+	}
+
+	id := fmt.Sprintf("img-%x", sha1.Sum(buf.Bytes()))[:16]
+	if !sc.symbols[id] {
+		b := img.Bounds()
+		fmt.Fprintf(&sc.defs, `<symbol id="%s" viewBox="0 0 %d %d"><image width="%d" height="%d" xlink:href="data:image/png;base64,%s"/></symbol>`+"\n",
+			id, b.Dx(), b.Dy(), b.Dx(), b.Dy(), base64.StdEncoding.EncodeToString(buf.Bytes()))
+		sc.symbols[id] = true
+	}
+
+	fmt.Fprintf(&sc.body, `<use xlink:href="#%s" x="%s" y="%s" width="%s" height="%s"/>`+"\n",
+		id, ftoa(x), ftoa(y), ftoa(w), ftoa(h))
+}
+
+// DrawPath fills and/or strokes the polyline.
+func (sc *svgCanvas) DrawPath(pts []gg.Point, closed bool, st Style) {
+	if len(pts) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	for i, pt := range pts {
+		if i == 0 {
+			sb.WriteString("M")
+		} else {
+			sb.WriteString(" L")
+		}
+		sb.WriteString(ftoa(pt.X))
+		sb.WriteString(",")
+		sb.WriteString(ftoa(pt.Y))
+	}
+	if closed {
+		sb.WriteString(" Z")
+	}
+
+	fmt.Fprintf(&sc.body, `<path d="%s"%s/>`+"\n", sb.String(), styleAttrs(st))
+}
+
+// DrawRoundedRect fills and/or strokes the rectangle.
+func (sc *svgCanvas) DrawRoundedRect(x, y, w, h, r float64, st Style) {
+	fmt.Fprintf(&sc.body, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s"%s/>`+"\n",
+		ftoa(x), ftoa(y), ftoa(w), ftoa(h), ftoa(r), styleAttrs(st))
+}
+
+// DrawString draws the text at the specified anchor point.
+func (sc *svgCanvas) DrawString(s string, x, y, ax, ay float64, ts TextStyle) {
+	sc.ruler.SetFontFace(truetype.NewFace(ts.Font, &truetype.Options{Size: ts.Size}))
+	w, h := sc.ruler.MeasureString(s)
+
+	var rotate string
+	if ts.Angle != 0 {
+		rotate = fmt.Sprintf(` transform="rotate(%s %s %s)"`, ftoa(ts.Angle), ftoa(x), ftoa(y))
+	}
+
+	x -= ax * w
+	y += ay * h
+
+	var family, esc bytes.Buffer
+	xml.EscapeText(&family, []byte(ts.Font.Name(truetype.NameIDFontFamily)))
+	xml.EscapeText(&esc, []byte(s))
+
+	fmt.Fprintf(&sc.body, `<text x="%s" y="%s" font-family="%s, sans-serif" font-size="%s"%s%s>%s</text>`+"\n",
+		ftoa(x), ftoa(y), family.String(), ftoa(ts.Size), fillAttrs(ts.Color), rotate, esc.String())
+}
+
+// Encode writes the SVG document, or returns the
+// error occurred drawing the images (if any).
+func (sc *svgCanvas) Encode(w io.Writer) error {
+	if sc.err != nil {
+		return sc.err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		sc.width, sc.height, sc.width, sc.height)
+	if sc.defs.Len() > 0 {
+		buf.WriteString("<defs>\n")
+		buf.Write(sc.defs.Bytes())
+		buf.WriteString("</defs>\n")
+	}
+	fmt.Fprintf(&buf, `<g transform="translate(%d,%d)">`+"\n", sc.margin, sc.margin)
+	buf.Write(sc.body.Bytes())
+	buf.WriteString("</g>\n</svg>\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// fillAttrs returns the SVG fill attributes for the hex color.
+func fillAttrs(hex string) string {
+	if hex == "" {
+		return ` fill="none"`
+	}
+
+	c := parseHexColor(hex)
+	res := fmt.Sprintf(` fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A < 255 {
+		res += fmt.Sprintf(` fill-opacity="%s"`, ftoa(float64(c.A)/255))
+	}
+	return res
+}
+
+// styleAttrs returns the SVG fill and stroke attributes for the style.
+func styleAttrs(st Style) string {
+	res := fillAttrs(st.Fill)
+	if st.Stroke == "" || st.StrokeWidth <= 0 {
+		return res
+	}
+
+	c := parseHexColor(st.Stroke)
+	res += fmt.Sprintf(` stroke="#%02x%02x%02x" stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"`,
+		c.R, c.G, c.B, ftoa(st.StrokeWidth))
+	if c.A < 255 {
+		res += fmt.Sprintf(` stroke-opacity="%s"`, ftoa(float64(c.A)/255))
+	}
+	if st.Dashes > 0 {
+		res += fmt.Sprintf(` stroke-dasharray="%s"`, ftoa(st.Dashes))
+	}
+	return res
+}
+
+// ftoa formats a float with at most two decimals.
+func ftoa(val float64) string {
+	res := fmt.Sprintf("%.2f", val)
+	res = strings.TrimRight(res, "0")
+	return strings.TrimSuffix(res, ".")
+}
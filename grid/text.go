@@ -0,0 +1,165 @@
+package grid
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+)
+
+// measure returns the width and the font height of the text.
+func (g *Grid) measure(s string, ts TextStyle) (w, h float64) {
+	g.ruler.SetFontFace(truetype.NewFace(ts.Font, &truetype.Options{Size: ts.Size}))
+	return g.ruler.MeasureString(s)
+}
+
+// measureMultiline returns the size of the text block.
+func (g *Grid) measureMultiline(s string, lineSpacing float64, ts TextStyle) (w, h float64) {
+	g.ruler.SetFontFace(truetype.NewFace(ts.Font, &truetype.Options{Size: ts.Size}))
+	return g.ruler.MeasureMultilineString(s, lineSpacing)
+}
+
+// wordWrap splits the text in lines not wider than width.
+func (g *Grid) wordWrap(s string, width float64, ts TextStyle) []string {
+	g.ruler.SetFontFace(truetype.NewFace(ts.Font, &truetype.Options{Size: ts.Size}))
+	return g.ruler.WordWrap(s, width)
+}
+
+// drawStringWrapped word-wraps the text and draws it on the canvas,
+// the same way gg.DrawStringWrapped does; rotated text blocks
+// turn about the anchor point.
+func (g *Grid) drawStringWrapped(s string, x, y, ax, ay, width, lineSpacing float64, align gg.Align, ts TextStyle) {
+	pivot := gg.Point{X: x, Y: y}
+	sin, cos := math.Sincos(gg.Radians(ts.Angle))
+
+	lines := g.wordWrap(s, width, ts)
+	_, fh := g.measure("", ts)
+
+	h := float64(len(lines)) * fh * lineSpacing
+	h -= (lineSpacing - 1) * fh
+
+	x -= ax * width
+	y -= ay * h
+	switch align {
+	case gg.AlignLeft:
+		ax = 0
+	case gg.AlignCenter:
+		ax = 0.5
+		x += width / 2
+	case gg.AlignRight:
+		ax = 1
+		x += width
+	}
+
+	for _, line := range lines {
+		// each line anchor turns with the whole block
+		dx, dy := x-pivot.X, y-pivot.Y
+		g.canvas.DrawString(line, pivot.X+dx*cos-dy*sin, pivot.Y+dx*sin+dy*cos, ax, 1, ts)
+		y += fh * lineSpacing
+	}
+}
+
+const (
+	// AnchorBelow draws the label under the cell.
+	AnchorBelow = "below"
+	// AnchorAbove draws the label over the cell.
+	AnchorAbove = "above"
+	// AnchorInside draws the label at the cell center.
+	AnchorInside = "inside"
+)
+
+// LabelOptions holds the settings used
+// to draw a text label in a grid cell.
+type LabelOptions struct {
+	fontSize float64
+	color    string
+	anchor   string
+	wrap     bool
+}
+
+// FontSize sets the label font size.
+func FontSize(val float64) func(*LabelOptions) {
+	return func(lo *LabelOptions) {
+		if val > 0 {
+			lo.fontSize = val
+		}
+	}
+}
+
+// TextColor sets the label color.
+func TextColor(hex string) func(*LabelOptions) {
+	return func(lo *LabelOptions) {
+		if hex != "" {
+			lo.color = hex
+		}
+	}
+}
+
+// Anchor sets the label position relative
+// to the cell (below, above or inside).
+func Anchor(val string) func(*LabelOptions) {
+	return func(lo *LabelOptions) {
+		if val != "" {
+			lo.anchor = strings.ToLower(val)
+		}
+	}
+}
+
+// Wrap word-wraps the label to the cell width.
+func Wrap(val bool) func(*LabelOptions) {
+	return func(lo *LabelOptions) {
+		lo.wrap = val
+	}
+}
+
+// DrawLabel draws the text relative to the cell at row and col.
+func (g *Grid) DrawLabel(text string, row, col int, opts ...func(*LabelOptions)) error {
+	if err := g.VerifyInBounds(row, col); err != nil {
+		return err
+	}
+
+	lo := LabelOptions{
+		fontSize: 0.18 * g.CellSize(),
+		color:    "#161615",
+		anchor:   AnchorBelow,
+	}
+	for _, opt := range opts {
+		opt(&lo)
+	}
+
+	ts := TextStyle{Font: g.font, Size: lo.fontSize, Color: lo.color}
+
+	width := g.CellWidth()
+	if !lo.wrap {
+		width, _ = g.measureMultiline(text, 1, ts)
+	}
+
+	pad := 0.04 * g.CellSize()
+	center := g.CellCenter(row, col)
+
+	x, y, ay := center.X, center.Y, 0.5
+	switch lo.anchor {
+	case AnchorBelow:
+		y, ay = center.Y+0.5*g.CellHeight()+pad, 0
+	case AnchorAbove:
+		y, ay = center.Y-0.5*g.CellHeight()-pad, 1
+	case AnchorInside:
+	default:
+		return fmt.Errorf("invalid label anchor: %s", lo.anchor)
+	}
+
+	// labels of the outer cells must not end up outside the image
+	// (i.e. below the last row with no margin): move them inside
+	_, fh := g.measure("", ts)
+	h := float64(len(g.wordWrap(text, width, ts))) * fh
+	m := float64(g.margin)
+	top := math.Max(-m, math.Min(y-ay*h, float64(g.canvasHeight)+m-h))
+	left := math.Max(-m, math.Min(x-0.5*width, float64(g.canvasWidth)+m-width))
+	x, y = left+0.5*width, top+ay*h
+
+	g.drawStringWrapped(text, x, y, 0.5, ay, width, 1, gg.AlignCenter, ts)
+
+	return nil
+}
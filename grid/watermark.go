@@ -0,0 +1,258 @@
+package grid
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+)
+
+// Watermark positions.
+const (
+	PositionCenter      = "center"
+	PositionTopLeft     = "top-left"
+	PositionTopRight    = "top-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionBottomRight = "bottom-right"
+)
+
+// WatermarkOptions holds the settings used to draw the watermark.
+type WatermarkOptions struct {
+	position string
+	color    string
+	angle    float64
+	size     float64
+	font     *truetype.Font
+	tiled    bool
+	image    image.Image
+	opacity  float64
+}
+
+// WatermarkPosition sets where the watermark is drawn (center,
+// top-left, top-right, bottom-left or bottom-right).
+func WatermarkPosition(val string) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		if val != "" {
+			wo.position = strings.ToLower(val)
+		}
+	}
+}
+
+// WatermarkColor sets the watermark text color (alpha included).
+func WatermarkColor(hex string) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		if hex != "" {
+			wo.color = hex
+		}
+	}
+}
+
+// WatermarkAngle rotates the watermark clockwise by the
+// specified angle in degrees (i.e. -45 for a diagonal text).
+func WatermarkAngle(deg float64) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		wo.angle = deg
+	}
+}
+
+// WatermarkSize sets the watermark font size (or
+// the image width) in pixels.
+func WatermarkSize(val float64) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		if val > 0 {
+			wo.size = val
+		}
+	}
+}
+
+// WatermarkFont sets the watermark font.
+func WatermarkFont(f *truetype.Font) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		if f != nil {
+			wo.font = f
+		}
+	}
+}
+
+// WatermarkTiled repeats the watermark across the whole image.
+func WatermarkTiled(val bool) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		wo.tiled = val
+	}
+}
+
+// WatermarkOpacity sets the watermark opacity (from 0 to 1);
+// for the text it scales the alpha of the color.
+func WatermarkOpacity(val float64) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		wo.opacity = math.Max(0, math.Min(1, val))
+	}
+}
+
+// WatermarkImage draws the image (i.e. a logo) with
+// the specified opacity (from 0 to 1) instead of the text.
+func WatermarkImage(img image.Image, opacity float64) func(*WatermarkOptions) {
+	return func(wo *WatermarkOptions) {
+		wo.image = img
+		wo.opacity = math.Max(0, math.Min(1, opacity))
+	}
+}
+
+// DrawWatermark draws the watermark: the text set with
+// the Watermark option or the image set with WatermarkImage.
+func (g *Grid) DrawWatermark(opts ...func(*WatermarkOptions)) {
+	wo := WatermarkOptions{
+		position: PositionCenter,
+		color:    "#c0c0c088",
+		font:     g.font,
+		opacity:  1,
+	}
+	for _, opt := range opts {
+		opt(&wo)
+	}
+
+	if wo.image != nil {
+		g.drawWatermarkImage(wo)
+		return
+	}
+
+	if g.watermark == "" {
+		return
+	}
+
+	const lineSpacing = 1.2
+
+	w, h := float64(g.imageWidth), float64(g.imageHeight)
+
+	ts := TextStyle{
+		Font:  wo.font,
+		Size:  wo.size,
+		Color: wo.color,
+		Angle: wo.angle,
+	}
+
+	if wo.opacity < 1 {
+		c := parseHexColor(ts.Color)
+		c.A = uint8(math.Round(float64(c.A) * wo.opacity))
+		ts.Color = fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+
+	if ts.Size == 0 {
+		ts.Size = 0.15 * math.Min(w, h)
+		if wo.tiled || wo.position != PositionCenter {
+			ts.Size = 0.05 * math.Min(w, h)
+		}
+	}
+
+	tw, th := g.measureMultiline(g.watermark, lineSpacing, ts)
+
+	if wo.tiled {
+		g.tile(tw, th, wo.angle, func(x, y float64) {
+			g.drawStringWrapped(g.watermark, x, y, 0.5, 0.5, tw, lineSpacing, gg.AlignCenter, ts)
+		})
+		return
+	}
+
+	x, y, ax, ay := g.watermarkAnchor(wo.position)
+	if wo.position == PositionCenter {
+		g.drawStringWrapped(g.watermark, x, y, ax, ay, math.Min(tw, th), lineSpacing, gg.AlignCenter, ts)
+		return
+	}
+
+	align := gg.AlignLeft
+	if ax == 1 {
+		align = gg.AlignRight
+	}
+	g.drawStringWrapped(g.watermark, x, y, ax, ay, tw, lineSpacing, align, ts)
+}
+
+// drawWatermarkImage draws the image watermark.
+func (g *Grid) drawWatermarkImage(wo WatermarkOptions) {
+	img := wo.image
+	w, h := float64(g.imageWidth), float64(g.imageHeight)
+
+	// larger images are shrinked to a fraction of the canvas
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	max := 0.3 * math.Min(w, h)
+	if wo.tiled || wo.position != PositionCenter {
+		max = 0.15 * math.Min(w, h)
+	}
+	if wo.size > 0 {
+		max = wo.size
+	}
+	if iw > max || wo.size > 0 {
+		iw, ih = max, math.Round(ih*max/iw)
+	}
+
+	if iw != float64(img.Bounds().Dx()) {
+		img = imaging.Resize(img, int(iw), int(ih), imaging.Lanczos)
+	}
+
+	// imaging rotates counter-clockwise
+	if wo.angle != 0 {
+		img = imaging.Rotate(img, -wo.angle, color.Transparent)
+		iw, ih = float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	}
+
+	if wo.opacity < 1 {
+		img = fade(img, wo.opacity)
+	}
+
+	if wo.tiled {
+		g.tile(iw, ih, 0, func(x, y float64) {
+			g.canvas.DrawImage(img, x-0.5*iw, y-0.5*ih, iw, ih)
+		})
+		return
+	}
+
+	x, y, ax, ay := g.watermarkAnchor(wo.position)
+	g.canvas.DrawImage(img, x-ax*iw, y-ay*ih, iw, ih)
+}
+
+// watermarkAnchor returns the anchor point of the watermark;
+// the center is the grid center, the corners are the image ones.
+func (g *Grid) watermarkAnchor(position string) (x, y, ax, ay float64) {
+	w, h := float64(g.imageWidth), float64(g.imageHeight)
+	m := float64(g.margin)
+	pad := 0.02 * math.Min(w, h)
+
+	switch position {
+	case PositionTopLeft:
+		return -m + pad, -m + pad, 0, 0
+	case PositionTopRight:
+		return w - m - pad, -m + pad, 1, 0
+	case PositionBottomLeft:
+		return -m + pad, h - m - pad, 0, 1
+	case PositionBottomRight:
+		return w - m - pad, h - m - pad, 1, 1
+	default:
+		return 0.5 * (w - 2*m), 0.5 * (h - 2*m), 0.5, 0.5
+	}
+}
+
+// tile calls fn with the centers of a brick pattern of boxes
+// (w x h rotated by angle, spaced apart) covering the whole image.
+func (g *Grid) tile(w, h, angle float64, fn func(x, y float64)) {
+	sin, cos := math.Sincos(gg.Radians(angle))
+	bw := math.Abs(w*cos) + math.Abs(h*sin)
+	bh := math.Abs(w*sin) + math.Abs(h*cos)
+
+	m := float64(g.margin)
+	gap := math.Min(w, h)
+	sx, sy := bw+gap, bh+gap
+
+	for i, y := 0, -m+0.5*sy; y < float64(g.imageHeight)-m+sy; i, y = i+1, y+sy {
+		x := -m + 0.5*sx
+		if i%2 == 1 {
+			x -= 0.5 * sx
+		}
+		for ; x < float64(g.imageWidth)-m+sx; x += sx {
+			fn(x, y)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package tilemap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseArt decodes a layout drawn as a block of characters;
+// each character is looked up in the legend, spaces are empty
+// cells. All rows must have the same width; blank lines (the
+// YAML block scalars may drop their spaces) are empty rows.
+func parseArt(src string, legend map[string]tileRef) (cells []cell, cols, rows int, err error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, 0, 0, fmt.Errorf("empty layout")
+	}
+
+	// only the final line break of the block is not a row
+	src = strings.TrimSuffix(strings.Replace(src, "\r\n", "\n", -1), "\n")
+	lines := strings.Split(src, "\n")
+
+	for _, line := range lines {
+		if n := len([]rune(line)); n > cols {
+			cols = n
+		}
+	}
+	rows = len(lines)
+	cells = make([]cell, 0, cols*rows)
+
+	for i, line := range lines {
+		if line == "" {
+			line = strings.Repeat(" ", cols)
+		}
+
+		chars := []rune(line)
+		if len(chars) != cols {
+			err := fmt.Errorf("layout row %d has width %d, expected %d", i+1, len(chars), cols)
+			return nil, 0, 0, &layoutError{row: i, pos: -1, err: err}
+		}
+
+		for j, ch := range chars {
+			if ch == ' ' {
+				cells = append(cells, cell{})
+				continue
+			}
+
+			if _, ok := legend[string(ch)]; !ok {
+				err := fmt.Errorf("character %q at row %d, column %d not found in legend", ch, i+1, j+1)
+				return nil, 0, 0, &layoutError{row: i, pos: -1, err: err}
+			}
+			cells = append(cells, cell{name: string(ch)})
+		}
+	}
+
+	return cells, cols, rows, nil
+}
@@ -0,0 +1,170 @@
+package tilemap
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalArt(t *testing.T) {
+	src := `
+tile_size: 64
+legend:
+  L: aws_lambda
+  "-": link_horizontal
+  "|": link_vertical
+layout: |
+  L-L
+   | 
+  L  
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	if tm.cols != 3 || tm.rows != 3 {
+		t.Fatalf("got [%dx%d] want [3x3]", tm.cols, tm.rows)
+	}
+
+	want := []string{
+		"aws_lambda", "link_horizontal", "aws_lambda",
+		"", "link_vertical", "",
+		"aws_lambda", "", "",
+	}
+
+	for i, el := range tm.layers[0].layout {
+		if el.empty() {
+			if want[i] != "" {
+				t.Errorf("cell %d: got [empty] want [%v]", i, want[i])
+			}
+			continue
+		}
+
+		ref, err := tm.refOf(el)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref.id != want[i] {
+			t.Errorf("cell %d: got [%v] want [%v]", i, ref.id, want[i])
+		}
+	}
+}
+
+func TestArtWithNumericLayer(t *testing.T) {
+	src := `
+mapping:
+  1: aws_rds
+legend:
+  L: aws_lambda
+layout: |
+  LL
+  LL
+layers:
+  - name: numeric
+    layout: 1,0,0,1
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(tm.layers), 2; got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	want := []cell{{index: 1}, {}, {}, {index: 1}}
+	for i, el := range tm.layers[1].layout {
+		if el != want[i] {
+			t.Errorf("cell %d: got [%v] want [%v]", i, el, want[i])
+		}
+	}
+}
+
+func TestNumericLayoutWithLegend(t *testing.T) {
+	legend := map[string]tileRef{"1": {id: "aws_lambda"}, "0": {id: "aws_rds"}}
+
+	tests := []struct {
+		src  string
+		want []cell
+	}{
+		{"layout: 1,0,0,1", []cell{{index: 1}, {}, {}, {index: 1}}},
+		{"layout: 1 0 0 1", []cell{{index: 1}, {}, {}, {index: 1}}},
+		{"layout: 5", []cell{{index: 5}}},
+		{"layout: |\n  10\n  01\n", []cell{{name: "1"}, {name: "0"}, {name: "0"}, {name: "1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			var res struct {
+				Layout layoutSpec `yaml:"layout"`
+			}
+			if err := yaml.Unmarshal([]byte(tt.src), &res); err != nil {
+				t.Fatal(err)
+			}
+
+			got, _, _, err := res.Layout.resolve(legend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got [%v] want [%v]", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("cell %d: got [%v] want [%v]", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseArtEmptyRows(t *testing.T) {
+	legend := map[string]tileRef{"L": {id: "aws_lambda"}}
+
+	tests := []struct {
+		src        string
+		cols, rows int
+	}{
+		{"  \nLL\n", 2, 2},
+		{"LL\n  \n  \n", 2, 3},
+		{"\nLL\n\nLL", 2, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			cells, cols, rows, err := parseArt(tt.src, legend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cols != tt.cols || rows != tt.rows {
+				t.Errorf("got [%dx%d] want [%dx%d]", cols, rows, tt.cols, tt.rows)
+			}
+			if len(cells) != cols*rows {
+				t.Errorf("got [%v] want [%v]", len(cells), cols*rows)
+			}
+		})
+	}
+}
+
+func TestParseArtErrors(t *testing.T) {
+	legend := map[string]tileRef{"L": {id: "aws_lambda"}}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"LL\nL\n", "layout row 2 has width 1, expected 2"},
+		{"LX\n", `character 'X' at row 1, column 2 not found in legend`},
+		{"\n\n", "empty layout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			_, _, _, err := parseArt(tt.src, legend)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got [%v] want [%v]", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package tilemap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucasepe/tiles/tileset"
+)
+
+// Autotile neighbour bits with 4 neighbours.
+const (
+	bit4N = 1
+	bit4E = 2
+	bit4S = 4
+	bit4W = 8
+)
+
+// Autotile neighbour bits with 8 neighbours.
+const (
+	bitN  = 1
+	bitNE = 2
+	bitE  = 4
+	bitSE = 8
+	bitS  = 16
+	bitSW = 32
+	bitW  = 64
+	bitNW = 128
+)
+
+// autotiles returns all the autotile rule sets: the ones
+// declared in the tilemap take precedence over the tilesets ones.
+func (tm *TileMap) autotiles(repo []*tileset.Tileset) map[string]*tileset.Autotile {
+	res := make(map[string]*tileset.Autotile)
+	for _, ts := range repo {
+		for k, v := range ts.Autotiles {
+			if _, ok := res[k]; !ok {
+				res[k] = v
+			}
+		}
+	}
+
+	for k, v := range tm.autotileRules {
+		res[k] = v
+	}
+
+	return res
+}
+
+// autotile returns the tile reference and the transforms
+// picked by the rule set for the cell at row and col.
+func (tm *TileMap) autotile(ly *layer, row, col int, name string, rules map[string]*tileset.Autotile) (tileRef, cell, error) {
+	at, ok := rules[name]
+	if !ok {
+		return tileRef{}, cell{}, fmt.Errorf("autotile %q not found", name)
+	}
+
+	mask := tm.autotileMask(ly, row, col, name, at.Neighbours)
+
+	spec, ok := at.Rules[mask]
+	if !ok {
+		spec = at.Default
+	}
+	if spec == "" {
+		return tileRef{}, cell{}, fmt.Errorf("autotile %q: no rule for mask %d at cell (%d, %d)", name, mask, row, col)
+	}
+
+	parts := strings.Split(spec, ":")
+
+	var res cell
+	if err := res.parseTransforms(parts[1:]); err != nil {
+		return tileRef{}, cell{}, fmt.Errorf("autotile %q: %s in rule %q", name, err, spec)
+	}
+
+	return tileRef{id: parts[0]}, res, nil
+}
+
+// autotileMask computes the bitmask of the neighbours
+// that belong to the same autotile of the cell at row and col.
+func (tm *TileMap) autotileMask(ly *layer, row, col int, name string, neighbours int) int {
+	same := func(r, c int) bool {
+		if r < 0 || r >= tm.rows || c < 0 || c >= tm.cols {
+			return false
+		}
+
+		pos := r*tm.cols + c
+		if pos >= len(ly.layout) {
+			return false
+		}
+
+		el := ly.layout[pos]
+		if el.empty() {
+			return false
+		}
+
+		ref, err := tm.refOf(el)
+		return err == nil && ref.autotile == name
+	}
+
+	n, e := same(row-1, col), same(row, col+1)
+	s, w := same(row+1, col), same(row, col-1)
+
+	if neighbours != 8 {
+		return boolToBit(n, bit4N) | boolToBit(e, bit4E) | boolToBit(s, bit4S) | boolToBit(w, bit4W)
+	}
+
+	res := boolToBit(n, bitN) | boolToBit(e, bitE) | boolToBit(s, bitS) | boolToBit(w, bitW)
+	res |= boolToBit(n && e && same(row-1, col+1), bitNE)
+	res |= boolToBit(s && e && same(row+1, col+1), bitSE)
+	res |= boolToBit(s && w && same(row+1, col-1), bitSW)
+	res |= boolToBit(n && w && same(row-1, col-1), bitNW)
+
+	return res
+}
+
+func boolToBit(val bool, bit int) int {
+	if val {
+		return bit
+	}
+	return 0
+}
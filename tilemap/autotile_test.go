@@ -0,0 +1,128 @@
+package tilemap
+
+import (
+	"testing"
+
+	"github.com/lucasepe/tiles/tileset"
+	"gopkg.in/yaml.v2"
+)
+
+func TestAutotile(t *testing.T) {
+	src := `
+cols: 3
+rows: 3
+mapping:
+  1: { autotile: link }
+  2: { autotile: wall }
+autotiles:
+  link:
+    neighbours: 4
+    default: link_cross
+    rules:
+      5: link_vertical
+      10: link_horizontal
+      4: link_vertical_arrow_up:r180
+  wall:
+    neighbours: 8
+    rules:
+      0: wall_single
+layout: >
+  0,1,0
+  1,1,1
+  2,1,0
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := tm.autotiles(nil)
+	ly := tm.layers[0]
+
+	tests := []struct {
+		row, col int
+		name     string
+		id       string
+		rotation int
+	}{
+		{0, 1, "link", "link_vertical_arrow_up", 180},
+		{1, 0, "link", "link_cross", 0},
+		{1, 1, "link", "link_cross", 0},
+		{2, 1, "link", "link_cross", 0},
+		{2, 0, "wall", "wall_single", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			ref, el, err := tm.autotile(ly, tt.row, tt.col, tt.name, rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ref.id != tt.id {
+				t.Errorf("got [%v] want [%v]", ref.id, tt.id)
+			}
+			if el.rotation != tt.rotation {
+				t.Errorf("got [%v] want [%v]", el.rotation, tt.rotation)
+			}
+		})
+	}
+}
+
+func TestAutotileMask(t *testing.T) {
+	tm := TileMap{
+		cols: 3, rows: 3,
+		mapping: map[int]tileRef{1: {autotile: "a"}},
+	}
+
+	layout, err := parseLayout("1,1,0,1,1,1,0,1,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ly := &layer{layout: layout}
+
+	tests := []struct {
+		row, col   int
+		neighbours int
+		want       int
+	}{
+		{1, 1, 4, 1 | 2 | 4 | 8},
+		{0, 0, 4, 2 | 4},
+		{1, 1, 8, bitN | bitE | bitS | bitW | bitNW},
+		{0, 0, 8, bitE | bitS | bitSE},
+		{2, 1, 8, bitN},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			if got := tm.autotileMask(ly, tt.row, tt.col, "a", tt.neighbours); got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalAutotile(t *testing.T) {
+	tests := []struct {
+		src        string
+		neighbours int
+		ok         bool
+	}{
+		{`{ default: link_cross }`, 4, true},
+		{`{ neighbours: 8, rules: { 255: wall_full } }`, 8, true},
+		{`{ neighbours: 6 }`, 0, false},
+		{`{ neighbours: 4, rules: { 16: link_cross } }`, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			var at tileset.Autotile
+			err := yaml.Unmarshal([]byte(tt.src), &at)
+			if got := err == nil; got != tt.ok {
+				t.Fatalf("got [%v] want [%v] (%v)", got, tt.ok, err)
+			}
+			if tt.ok && at.Neighbours != tt.neighbours {
+				t.Errorf("got [%v] want [%v]", at.Neighbours, tt.neighbours)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package tilemap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tileset"
+)
+
+// endpoint is a connection end: a [row, col] pair or
+// a cell reference ('row,col' or a cell name).
+type endpoint struct {
+	ref      string
+	row, col int
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+func (ep *endpoint) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var rc [2]int
+	if err := unmarshal(&rc); err == nil {
+		ep.row, ep.col = rc[0], rc[1]
+		return nil
+	}
+
+	return unmarshal(&ep.ref)
+}
+
+// connection is a line drawn between two cells,
+// routed orthogonally around the occupied cells.
+type connection struct {
+	from, to endpoint
+	color    string
+	width    float64
+	dashes   float64
+	arrow    string
+	label    string
+}
+
+// drawOptions returns the grid options that apply the connection style.
+func (cn *connection) drawOptions() []func(*grid.ConnectorOptions) {
+	return []func(*grid.ConnectorOptions){
+		grid.ConnectorColor(cn.color),
+		grid.ConnectorWidth(cn.width),
+		grid.ConnectorDashes(cn.dashes),
+		grid.Arrows(cn.arrow == "start" || cn.arrow == "both",
+			cn.arrow == "" || cn.arrow == "end" || cn.arrow == "both"),
+		grid.ConnectorLabel(cn.label),
+	}
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// A connection is an object like:
+//
+//	{ from: [0, 1], to: db, color: "#333", dashes: 4, arrow: both, label: SQL }
+func (cn *connection) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		From   *endpoint `yaml:"from"`
+		To     *endpoint `yaml:"to"`
+		Color  string    `yaml:"color"`
+		Width  float64   `yaml:"width"`
+		Dashes float64   `yaml:"dashes"`
+		Arrow  string    `yaml:"arrow"`
+		Label  string    `yaml:"label"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	if aux.From == nil || aux.To == nil {
+		return fmt.Errorf("connection must have both 'from' and 'to' cells")
+	}
+
+	cn.arrow = strings.ToLower(aux.Arrow)
+	switch cn.arrow {
+	case "", "none", "start", "end", "both":
+	default:
+		return fmt.Errorf("invalid connection arrow %q", aux.Arrow)
+	}
+
+	cn.from = *aux.From
+	cn.to = *aux.To
+	cn.color = aux.Color
+	cn.width = aux.Width
+	cn.dashes = aux.Dashes
+	cn.label = aux.Label
+
+	return nil
+}
+
+// resolveConnections assigns the cell coordinates
+// to the ends referenced by a cell name or 'row,col'.
+func resolveConnections(src []*connection, names map[string][2]int) error {
+	for _, cn := range src {
+		for _, ep := range []*endpoint{&cn.from, &cn.to} {
+			if ep.ref == "" {
+				continue
+			}
+
+			row, col, err := resolveCell(ep.ref, names)
+			if err != nil {
+				return fmt.Errorf("connection: %s", err)
+			}
+			ep.row, ep.col = row, col
+		}
+	}
+
+	return nil
+}
+
+// renderConnections routes and draws all the connections.
+func (tm *TileMap) renderConnections(gr *grid.Grid, repo []*tileset.Tileset) error {
+	if len(tm.connections) == 0 {
+		return nil
+	}
+
+	if t := tm.gridType; t != "" && t != grid.TypeSquare {
+		return fmt.Errorf("connections are supported on square grids only, not %s", t)
+	}
+
+	occupied := tm.occupied(repo)
+	blocked := func(row, col int) bool {
+		return occupied[row*tm.cols+col]
+	}
+
+	for _, cn := range tm.connections {
+		from := [2]int{cn.from.row, cn.from.col}
+		to := [2]int{cn.to.row, cn.to.col}
+
+		path, err := grid.Route(tm.rows, tm.cols, from, to, blocked)
+		if err != nil {
+			return fmt.Errorf("connection from %v to %v: %s", from, to, err)
+		}
+
+		if err := gr.DrawConnector(path, cn.drawOptions()...); err != nil {
+			return fmt.Errorf("connection from %v to %v: %s", from, to, err)
+		}
+	}
+
+	return nil
+}
+
+// occupied returns the positions of the cells covered
+// by a tile (multi-cell tiles included) in any visible layer.
+func (tm *TileMap) occupied(repo []*tileset.Tileset) map[int]bool {
+	res := make(map[int]bool)
+
+	for _, ly := range tm.layers {
+		if !ly.visible {
+			continue
+		}
+
+		for pos, el := range ly.layout {
+			if el.empty() || pos >= tm.rows*tm.cols {
+				continue
+			}
+
+			cols, rows := 1, 1
+			if ref, err := tm.refOf(el); err == nil {
+				tile, _ := lookupTile(repo, ref.id)
+				cols, rows = spanOf(ref, tile)
+			}
+
+			r, c := pos/tm.cols, pos%tm.cols
+			for i := r; i < r+rows && i < tm.rows; i++ {
+				for j := c; j < c+cols && j < tm.cols; j++ {
+					res[i*tm.cols+j] = true
+				}
+			}
+		}
+	}
+
+	return res
+}
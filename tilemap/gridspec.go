@@ -0,0 +1,64 @@
+package tilemap
+
+import (
+	"github.com/lucasepe/tiles/grid"
+)
+
+// gridSpec describes the optional grid decorations:
+// the lines between cells, the border and the cell coordinates.
+type gridSpec struct {
+	lines        bool
+	lineColor    string
+	lineWidth    float64
+	lineDashes   float64
+	borderColor  string
+	borderWidth  float64
+	borderDashes float64
+	coords       bool
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+func (gs *gridSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		Lines        bool    `yaml:"lines"`
+		LineColor    string  `yaml:"line_color"`
+		LineWidth    float64 `yaml:"line_width"`
+		LineDashes   float64 `yaml:"line_dashes"`
+		BorderColor  string  `yaml:"border_color"`
+		BorderWidth  float64 `yaml:"border_width"`
+		BorderDashes float64 `yaml:"border_dashes"`
+		Coords       bool    `yaml:"coords"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	gs.lines = aux.Lines
+	gs.lineColor = aux.LineColor
+	gs.lineWidth = aux.LineWidth
+	gs.lineDashes = aux.LineDashes
+	gs.borderColor = aux.BorderColor
+	gs.borderWidth = aux.BorderWidth
+	gs.borderDashes = aux.BorderDashes
+	gs.coords = aux.Coords
+
+	return nil
+}
+
+// gridOptions returns the grid options that apply the style.
+func (gs *gridSpec) gridOptions() []func(*grid.Grid) {
+	return []func(*grid.Grid){
+		grid.Lines(gs.lineColor, gs.lineWidth, gs.lineDashes),
+		grid.Border(gs.borderColor, gs.borderWidth, gs.borderDashes),
+	}
+}
+
+// Debug turns on the cell coordinates overlay.
+func Debug(on bool) func(*TileMap) {
+	return func(tm *TileMap) {
+		if on {
+			tm.grid.coords = true
+		}
+	}
+}
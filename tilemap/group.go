@@ -0,0 +1,152 @@
+package tilemap
+
+import (
+	"fmt"
+
+	"github.com/lucasepe/tiles/grid"
+)
+
+// group is a boundary drawn around a rectangular range
+// of cells (i.e. a VPC or a subnet); groups can be nested.
+type group struct {
+	title        string
+	from, to     endpoint
+	borderColor  string
+	borderWidth  float64
+	borderDashes float64
+	fillColor    string
+	radius       float64
+	groups       []*group
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// A group is an object like:
+//
+//	{ title: VPC, from: [0, 0], to: web, border_color: "#147eba", border_dashes: 6 }
+func (gp *group) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		Title        string    `yaml:"title"`
+		From         *endpoint `yaml:"from"`
+		To           *endpoint `yaml:"to"`
+		BorderColor  string    `yaml:"border_color"`
+		BorderWidth  float64   `yaml:"border_width"`
+		BorderDashes float64   `yaml:"border_dashes"`
+		FillColor    string    `yaml:"fill_color"`
+		Radius       float64   `yaml:"radius"`
+		Groups       []*group  `yaml:"groups"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	if aux.From == nil || aux.To == nil {
+		return fmt.Errorf("group %q must have both 'from' and 'to' cells", aux.Title)
+	}
+
+	gp.title = aux.Title
+	gp.from = *aux.From
+	gp.to = *aux.To
+	gp.borderColor = aux.BorderColor
+	gp.borderWidth = aux.BorderWidth
+	gp.borderDashes = aux.BorderDashes
+	gp.fillColor = aux.FillColor
+	gp.radius = aux.Radius
+	gp.groups = aux.Groups
+
+	return nil
+}
+
+// bounds returns the top left and the bottom right cells.
+func (gp *group) bounds() (minRow, minCol, maxRow, maxCol int) {
+	minRow, maxRow = gp.from.row, gp.to.row
+	if minRow > maxRow {
+		minRow, maxRow = maxRow, minRow
+	}
+	minCol, maxCol = gp.from.col, gp.to.col
+	if minCol > maxCol {
+		minCol, maxCol = maxCol, minCol
+	}
+	return minRow, minCol, maxRow, maxCol
+}
+
+// drawOptions returns the grid options that apply the group
+// style; nested groups are moved inside their parents.
+func (gp *group) drawOptions(depth int, cellSize float64) []func(*grid.GroupOptions) {
+	return []func(*grid.GroupOptions){
+		grid.GroupTitle(gp.title),
+		grid.GroupBorder(gp.borderColor, gp.borderWidth, gp.borderDashes),
+		grid.GroupFill(gp.fillColor),
+		grid.GroupRadius(gp.radius),
+		grid.GroupInset(float64(depth) * 0.08 * cellSize),
+	}
+}
+
+// resolveGroups assigns the cell coordinates to the ends referenced
+// by a cell name or 'row,col' and verifies that the nested groups
+// are inside their parent.
+func resolveGroups(src []*group, parent *group, names map[string][2]int) error {
+	for _, gp := range src {
+		for _, ep := range []*endpoint{&gp.from, &gp.to} {
+			if ep.ref == "" {
+				continue
+			}
+
+			row, col, err := resolveCell(ep.ref, names)
+			if err != nil {
+				return fmt.Errorf("group %q: %s", gp.title, err)
+			}
+			ep.row, ep.col = row, col
+		}
+
+		if parent != nil {
+			r0, c0, r1, c1 := gp.bounds()
+			pr0, pc0, pr1, pc1 := parent.bounds()
+			if r0 < pr0 || c0 < pc0 || r1 > pr1 || c1 > pc1 {
+				return fmt.Errorf("group %q is not inside group %q", gp.title, parent.title)
+			}
+		}
+
+		if err := resolveGroups(gp.groups, gp, names); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkGroups calls fn for each group, parents first.
+func walkGroups(src []*group, depth int, fn func(gp *group, depth int) error) error {
+	for _, gp := range src {
+		if err := fn(gp, depth); err != nil {
+			return err
+		}
+		if err := walkGroups(gp.groups, depth+1, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderGroups draws the boundaries of all the groups.
+func (tm *TileMap) renderGroups(gr *grid.Grid) error {
+	return walkGroups(tm.groups, 0, func(gp *group, depth int) error {
+		r0, c0, r1, c1 := gp.bounds()
+		if err := gr.DrawGroup(r0, c0, r1, c1, gp.drawOptions(depth, gr.CellSize())...); err != nil {
+			return fmt.Errorf("group %q: %s", gp.title, err)
+		}
+		return nil
+	})
+}
+
+// renderGroupTitles draws the titles of all the groups.
+func (tm *TileMap) renderGroupTitles(gr *grid.Grid) error {
+	return walkGroups(tm.groups, 0, func(gp *group, depth int) error {
+		r0, c0, r1, c1 := gp.bounds()
+		if err := gr.DrawGroupTitle(r0, c0, r1, c1, gp.drawOptions(depth, gr.CellSize())...); err != nil {
+			return fmt.Errorf("group %q: %s", gp.title, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,123 @@
+package tilemap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/grid"
+	"gopkg.in/yaml.v2"
+)
+
+// label is a text caption drawn next to a cell.
+type label struct {
+	// ref is the cell reference the label is keyed by
+	ref      string
+	row, col int
+	text     string
+	fontSize float64
+	color    string
+	anchor   string
+	wrap     bool
+}
+
+// drawOptions returns the grid options that apply the label style.
+func (lb *label) drawOptions() []func(*grid.LabelOptions) {
+	return []func(*grid.LabelOptions){
+		grid.FontSize(lb.fontSize),
+		grid.TextColor(lb.color),
+		grid.Anchor(lb.anchor),
+		grid.Wrap(lb.wrap),
+	}
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// A label can be a plain text or an object like:
+//
+//	{ text: orders-api, font_size: 12, color: "#333", anchor: below, wrap: true }
+func (lb *label) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err == nil {
+		lb.text = text
+		return nil
+	}
+
+	aux := struct {
+		Text     string  `yaml:"text"`
+		FontSize float64 `yaml:"font_size"`
+		Color    string  `yaml:"color"`
+		Anchor   string  `yaml:"anchor"`
+		Wrap     bool    `yaml:"wrap"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	lb.anchor = strings.ToLower(aux.Anchor)
+	switch lb.anchor {
+	case "", grid.AnchorBelow, grid.AnchorAbove, grid.AnchorInside:
+	default:
+		// a type error lets the lenient decoding go on
+		return &yaml.TypeError{Errors: []string{
+			fmt.Sprintf("invalid label anchor %q (below, above or inside)", aux.Anchor),
+		}}
+	}
+
+	lb.text = aux.Text
+	lb.fontSize = aux.FontSize
+	lb.color = aux.Color
+	lb.wrap = aux.Wrap
+
+	return nil
+}
+
+// resolveLabels assigns the cell coordinates to each label.
+// Labels are keyed by 'row,col' or by a cell name.
+func resolveLabels(src map[string]*label, names map[string][2]int) ([]*label, error) {
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]*label, 0, len(src))
+	for _, k := range keys {
+		row, col, err := resolveCell(k, names)
+		if err != nil {
+			return nil, err
+		}
+
+		lb := src[k]
+		lb.ref, lb.row, lb.col = k, row, col
+		res = append(res, lb)
+	}
+
+	return res, nil
+}
+
+// resolveCell returns the coordinates of the cell
+// referenced by a cell name or by a 'row,col' string.
+func resolveCell(ref string, names map[string][2]int) (row, col int, err error) {
+	if rc, ok := names[ref]; ok {
+		return rc[0], rc[1], nil
+	}
+
+	parts := strings.Split(ref, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("cell %q not found", ref)
+	}
+
+	row, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cell %q", ref)
+	}
+
+	col, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cell %q", ref)
+	}
+
+	return row, col, nil
+}
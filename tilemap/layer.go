@@ -0,0 +1,289 @@
+package tilemap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lucasepe/tiles/grid"
+)
+
+// defaultLayerName is the name given to the implicit
+// layer defined by the top level 'layout' field.
+const defaultLayerName = "default"
+
+// layer describes a single level of tiles
+// composited onto the tilemap canvas.
+type layer struct {
+	name    string
+	spec    layoutSpec
+	layout  []cell
+	opacity float64
+	visible bool
+	offsetX int
+	offsetY int
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+func (ly *layer) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := struct {
+		Name    string     `yaml:"name"`
+		Layout  layoutSpec `yaml:"layout"`
+		Opacity *float64   `yaml:"opacity"`
+		Visible *bool      `yaml:"visible"`
+		OffsetX int        `yaml:"offset_x"`
+		OffsetY int        `yaml:"offset_y"`
+	}{}
+
+	err := unmarshal(&aux)
+	if err != nil {
+		return err
+	}
+
+	ly.name = aux.Name
+	ly.offsetX = aux.OffsetX
+	ly.offsetY = aux.OffsetY
+
+	ly.opacity = 1
+	if aux.Opacity != nil {
+		ly.opacity = *aux.Opacity
+	}
+	if ly.opacity < 0 || ly.opacity > 1 {
+		return fmt.Errorf("layer %q: opacity must be between 0 and 1", ly.name)
+	}
+
+	ly.visible = true
+	if aux.Visible != nil {
+		ly.visible = *aux.Visible
+	}
+
+	ly.spec = aux.Layout
+
+	return nil
+}
+
+// layoutSpec is a layout that can be written as a string of comma
+// (or space) separated indexes, or as a list of rows; each row is a
+// list (or a space separated string) of tile ids, aliases or indexes.
+// When the tilemap has a legend, the string is an ASCII art instead,
+// unless it is a comma separated list of indexes.
+type layoutSpec struct {
+	cells []cell
+	// art is the raw string form of the layout
+	art string
+	// numeric is true if the string form is a single line
+	// list of indexes
+	numeric bool
+	// err is the error decoding the layout, reported with the
+	// layer name (only if the layout is not an ASCII art)
+	err error
+	// ascii is true if the layout was decoded as an ASCII art
+	ascii bool
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+func (ls *layoutSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var src string
+	if err := unmarshal(&src); err == nil {
+		if strings.TrimSpace(src) == "" {
+			return nil
+		}
+
+		ls.art = src
+		ls.cells, ls.err = parseLayout(src)
+		ls.numeric = ls.err == nil && !strings.Contains(strings.TrimSuffix(src, "\n"), "\n")
+		return nil
+	}
+
+	var rows [][]string
+	if err := unmarshal(&rows); err != nil {
+		var lines []string
+		if err := unmarshal(&lines); err != nil {
+			ls.err = fmt.Errorf("layout must be a string or a list of rows")
+			return nil
+		}
+
+		rows = make([][]string, len(lines))
+		for i, el := range lines {
+			rows[i] = strings.FieldsFunc(el, func(r rune) bool {
+				return r == ',' || unicode.IsSpace(r)
+			})
+		}
+	}
+
+	ls.cells = []cell{}
+	for i, row := range rows {
+		for _, tok := range row {
+			el, err := parseNamedCell(strings.TrimSpace(tok))
+			if err != nil {
+				ls.cells, ls.err = nil, &layoutError{row: i, pos: -1, err: err}
+				return nil
+			}
+			ls.cells = append(ls.cells, el)
+		}
+	}
+
+	return nil
+}
+
+// empty returns true if no layout was specified.
+func (ls *layoutSpec) empty() bool {
+	return len(ls.cells) == 0 && ls.art == "" && ls.err == nil
+}
+
+// resolve returns the layout cells; if a legend is specified
+// the string form (unless numeric) is decoded as an ASCII art
+// and its size (columns and rows) is returned too.
+func (ls *layoutSpec) resolve(legend map[string]tileRef) (cells []cell, cols, rows int, err error) {
+	ls.ascii = len(legend) > 0 && ls.art != "" && !ls.numeric
+	if ls.ascii {
+		return parseArt(ls.art, legend)
+	}
+
+	return ls.cells, 0, 0, ls.err
+}
+
+// layoutError is an error decoding a layout entry, with its
+// row or its cell position (-1 if unknown) to locate it.
+type layoutError struct {
+	row, pos int
+	err      error
+}
+
+// Error implements the error interface.
+func (e *layoutError) Error() string {
+	return e.err.Error()
+}
+
+// cell is a single layout entry: the tile index (or
+// the tile id or alias) and its optional transformations.
+type cell struct {
+	index    int
+	name     string
+	rotation int
+	flipH    bool
+	flipV    bool
+	// fit and filter (optional) override the mapping ones
+	fit    string
+	filter string
+}
+
+// drawOptions returns the grid options
+// that apply the cell transformations.
+func (c cell) drawOptions() []func(*grid.DrawOptions) {
+	res := []func(*grid.DrawOptions){}
+	if c.flipH {
+		res = append(res, grid.FlipH())
+	}
+	if c.flipV {
+		res = append(res, grid.FlipV())
+	}
+	if c.rotation != 0 {
+		res = append(res, grid.Rotate(c.rotation))
+	}
+	return res
+}
+
+// parseLayout decodes a comma (or space) separated list of tile indexes.
+// Each index can be followed by one or more transform suffixes:
+//
+//	:r90, :r180, :r270 rotates the tile clockwise
+//	:fh, :fv flips the tile horizontally or vertically
+//	:cover, :stretch, ... sets the fit mode
+//	:nearest, :box, ... sets the resampling filter
+func parseLayout(src string) ([]cell, error) {
+	layout := strings.Split(strings.Replace(src, " ", ",", -1), ",")
+
+	res := make([]cell, len(layout))
+	for i := 0; i < len(layout); i++ {
+		el, err := parseCell(strings.TrimSpace(layout[i]))
+		if err != nil {
+			return nil, &layoutError{row: -1, pos: i, err: err}
+		}
+		res[i] = el
+	}
+
+	return res, nil
+}
+
+// parseCell decodes a single layout entry (i.e. 30:r90:fh).
+func parseCell(src string) (cell, error) {
+	parts := strings.Split(src, ":")
+
+	num, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return cell{}, err
+	}
+
+	res := cell{index: num}
+	if err := res.parseTransforms(parts[1:]); err != nil {
+		return cell{}, fmt.Errorf("%s in cell %q", err, src)
+	}
+
+	return res, nil
+}
+
+// parseNamedCell decodes a single layout entry that
+// references a tile id, an alias or an index (i.e. aws_lambda:r90);
+// '.' and '_' stand for an empty cell.
+func parseNamedCell(src string) (cell, error) {
+	parts := strings.Split(src, ":")
+
+	var res cell
+	switch head := parts[0]; head {
+	case "", ".", "_":
+		return cell{}, nil
+	default:
+		if num, err := strconv.Atoi(head); err == nil {
+			res.index = num
+		} else {
+			res.name = head
+		}
+	}
+
+	if err := res.parseTransforms(parts[1:]); err != nil {
+		return cell{}, fmt.Errorf("%s in cell %q", err, src)
+	}
+
+	return res, nil
+}
+
+// empty returns true if the cell has no tile.
+func (c cell) empty() bool {
+	return c.name == "" && c.index <= 0
+}
+
+// parseTransforms decodes the transform suffixes (r90, fh, ...);
+// a fit mode (i.e. cover) or a resampling filter (i.e. nearest)
+// sets how this tile is sized into its cells.
+func (c *cell) parseTransforms(list []string) error {
+	for _, el := range list {
+		el = strings.ToLower(el)
+		if grid.CheckFit(el, "") == nil {
+			c.fit = el
+			continue
+		}
+		if grid.CheckFit("", el) == nil {
+			c.filter = el
+			continue
+		}
+
+		switch el {
+		case "r90":
+			c.rotation = 90
+		case "r180":
+			c.rotation = 180
+		case "r270":
+			c.rotation = 270
+		case "fh":
+			c.flipH = true
+		case "fv":
+			c.flipV = true
+		default:
+			return fmt.Errorf("invalid transform %q", el)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package tilemap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/grid"
+)
+
+// tileRef is a mapping entry: the tile identifier
+// and (optionally) the number of cells it spans;
+// or the name of the autotile rule set. Fit and filter
+// (optional) override the tilemap ones.
+type tileRef struct {
+	id       string
+	cols     int
+	rows     int
+	autotile string
+	fit      string
+	filter   string
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// A mapping entry can be a plain tile id or an object like:
+//
+//	{ id: aws_vpc, span: 2x2 }
+//	{ autotile: link }
+//	{ id: hero, fit: upscale-nearest, filter: nearest }
+func (tr *tileRef) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var id string
+	if err := unmarshal(&id); err == nil {
+		tr.id = id
+		return nil
+	}
+
+	aux := struct {
+		ID       string `yaml:"id"`
+		Span     string `yaml:"span"`
+		Autotile string `yaml:"autotile"`
+		Fit      string `yaml:"fit"`
+		Filter   string `yaml:"filter"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	tr.id = aux.ID
+	tr.autotile = aux.Autotile
+	tr.fit = strings.ToLower(aux.Fit)
+	tr.filter = strings.ToLower(aux.Filter)
+	if err := grid.CheckFit(tr.fit, tr.filter); err != nil {
+		return err
+	}
+
+	if aux.Span == "" {
+		return nil
+	}
+
+	var err error
+	tr.cols, tr.rows, err = parseSpan(aux.Span)
+	return err
+}
+
+// parseSpan decodes a span like '3x1' (columns x rows).
+func parseSpan(src string) (cols, rows int, err error) {
+	parts := strings.Split(strings.ToLower(src), "x")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid span %q, expected COLSxROWS", src)
+	}
+
+	cols, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid span %q, expected COLSxROWS", src)
+	}
+
+	rows, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid span %q, expected COLSxROWS", src)
+	}
+
+	if cols < 1 || rows < 1 {
+		return 0, 0, fmt.Errorf("invalid span %q, must be at least 1x1", src)
+	}
+
+	return cols, rows, nil
+}
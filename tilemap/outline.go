@@ -0,0 +1,200 @@
+package tilemap
+
+import (
+	"strings"
+)
+
+// outlineNode is a key (or a list item) of a YAML document
+// with the line where it is defined; it is used only to
+// locate the problems reported by Validate.
+type outlineNode struct {
+	key      string
+	line     int
+	indent   int
+	inline   bool
+	children []*outlineNode
+	// text holds the lines of the value: the inline value and
+	// its continuation lines, or the block scalar lines
+	text []string
+}
+
+// outline scans the YAML source and returns the tree of its keys.
+// It understands block mappings, block sequences (items have
+// the '-' key) and keeps the values spanning more lines (block
+// scalars or wrapped plain scalars) as text.
+func outline(src []byte) *outlineNode {
+	root := &outlineNode{indent: -1}
+	stack := []*outlineNode{root}
+
+	var scalar *outlineNode
+	for i, line := range strings.Split(string(src), "\n") {
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+		content = strings.TrimSpace(content)
+
+		// inside a multi-line value
+		if scalar != nil {
+			if content == "" || indent > scalar.indent {
+				scalar.text = append(scalar.text, content)
+				continue
+			}
+			scalar = nil
+		}
+
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		// list items: the item node holds the
+		// inline key (if any) as its first child
+		for strings.HasPrefix(content, "- ") || content == "-" {
+			item := &outlineNode{key: "-", line: i + 1, indent: indent}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, item)
+			stack = append(stack, item)
+
+			content = strings.TrimSpace(strings.TrimPrefix(content, "-"))
+			indent += 2
+		}
+
+		key, value, ok := splitKey(content)
+		if !ok {
+			continue
+		}
+
+		node := &outlineNode{
+			key: key, line: i + 1, indent: indent,
+			inline: value != "" && !strings.HasPrefix(value, "|") && !strings.HasPrefix(value, ">"),
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+
+		if node.inline {
+			node.text = []string{value}
+		}
+		if value != "" {
+			scalar = node
+		}
+	}
+
+	return root
+}
+
+// splitKey splits a 'key: value' line; quoted keys are unquoted.
+func splitKey(content string) (key, value string, ok bool) {
+	if strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[") {
+		return "", "", false
+	}
+
+	idx := strings.Index(content, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(content, ":") {
+			return "", "", false
+		}
+		idx = len(content) - 1
+	}
+
+	key = strings.Trim(strings.TrimSpace(content[:idx]), `"'`)
+	value = strings.TrimSpace(content[idx+1:])
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+
+	return key, value, true
+}
+
+// find returns the node at the specified path of keys;
+// returns nil if not found (it is safe to call on nil).
+func (n *outlineNode) find(path ...string) *outlineNode {
+	if n == nil {
+		return nil
+	}
+
+	if len(path) == 0 {
+		return n
+	}
+
+	for _, el := range n.children {
+		if el.key == path[0] {
+			return el.find(path[1:]...)
+		}
+	}
+
+	return nil
+}
+
+// item returns the i-th list item child.
+func (n *outlineNode) item(i int) *outlineNode {
+	if n == nil {
+		return nil
+	}
+
+	for _, el := range n.children {
+		if el.key != "-" {
+			continue
+		}
+		if i == 0 {
+			return el
+		}
+		i--
+	}
+
+	return nil
+}
+
+// lineOf returns the node line, or zero for nil nodes.
+func (n *outlineNode) lineOf() int {
+	if n == nil {
+		return 0
+	}
+	return n.line
+}
+
+// rowLine returns the line of the specified row of a layout:
+// the key line for inline layouts, the item line for lists of
+// rows, the row line for block scalars (ASCII art).
+func (n *outlineNode) rowLine(row int) int {
+	if n == nil {
+		return 0
+	}
+	if n.inline {
+		return n.line
+	}
+	if it := n.item(row); it != nil {
+		return it.line
+	}
+	if row >= len(n.text) {
+		return n.line
+	}
+	return n.line + 1 + row
+}
+
+// cellLine returns the line of the specified cell of a list of
+// indexes: the cells may be spread over any number of lines
+// (folded or wrapped values), so they are counted line by line.
+func (n *outlineNode) cellLine(pos int) int {
+	if n == nil {
+		return 0
+	}
+
+	first := n.line + 1
+	if n.inline {
+		first = n.line
+	}
+
+	for i, line := range n.text {
+		pos -= len(strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}))
+		if pos < 0 {
+			return first + i
+		}
+	}
+
+	return n.line
+}
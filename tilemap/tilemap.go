@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"image"
 	"io"
-	"strconv"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/golang/freetype/truetype"
 	"github.com/lucasepe/tiles/data"
 	"github.com/lucasepe/tiles/grid"
 	"github.com/lucasepe/tiles/tileset"
@@ -17,113 +19,521 @@ type TileMap struct {
 	cols      int
 	rows      int
 	tileSize  int
-	layout    []int
+	layers    []*layer
 	margin    int
-	watermark string
+	watermark watermark
 	bgColor   string
-	mapping   map[int]string
+	mapping   map[int]tileRef
+	aliases   map[string]tileRef
 	atlasList []string
+	format    string
+	font      string
+	names     map[string][2]int
+	labels    []*label
+
+	autotileRules map[string]*tileset.Autotile
+
+	// tileWidth and tileHeight (optional) make the cells rectangular
+	tileWidth  int
+	tileHeight int
+
+	// fit and filter (optional) size the images into the cells
+	fit    string
+	filter string
+
+	grid gridSpec
+
+	gridType       string
+	hexOrientation string
+	hexOffset      string
+	isoProjection  string
+	isoRatio       float64
+
+	connections []*connection
+	groups      []*group
+
+	// repo holds the preloaded tilesets (i.e. from a TMX file)
+	repo []*tileset.Tileset
+
+	// lenient keeps decoding after a problem, collected in issues
+	// (used by Validate to report all of them)
+	lenient bool
+	issues  []decodeIssue
+}
+
+// Format sets the output format used by Render (png or svg).
+func Format(name string) func(*TileMap) {
+	return func(tm *TileMap) {
+		tm.format = name
+	}
 }
 
-func (tm *TileMap) Render(wr io.Writer) error {
-	repo, err := tileset.Load(tm.atlasList...)
+// Render draws the tilemap and writes the
+// image (PNG by default) to the specified writer;
+// the options apply to this call only.
+func (tm *TileMap) Render(wr io.Writer, opts ...func(*TileMap)) error {
+	cfg := *tm
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg.render(wr)
+}
+
+// render draws the tilemap with its current settings.
+func (tm *TileMap) render(wr io.Writer) error {
+	repo, err := tm.tilesets()
 	if err != nil {
 		return err
 	}
 
-	gr, err := grid.NewGrid(tm.rows, tm.cols, tm.tileSize,
+	font, err := loadFont(tm.font)
+	if err != nil {
+		return err
+	}
+
+	gridOpts := []func(*grid.Grid){
+		grid.Font(font),
 		grid.Background(tm.bgColor),
 		grid.Margin(tm.margin),
-		grid.Watermark(tm.watermark))
+		grid.Watermark(tm.watermark.text),
+		grid.Format(tm.format),
+	}
+	gridOpts = append(gridOpts, tm.grid.gridOptions()...)
+	switch tm.gridType {
+	case "", grid.TypeSquare:
+		gridOpts = append(gridOpts, grid.CellRect(tm.tileRect()))
+	case grid.TypeHex:
+		gridOpts = append(gridOpts, grid.Hex(tm.hexOrientation, tm.hexOffset))
+	case grid.TypeIsometric:
+		gridOpts = append(gridOpts, grid.Isometric(tm.isoProjection, tm.isoRatio))
+	}
+
+	gr, err := grid.NewGrid(tm.rows, tm.cols, tm.tileSize, gridOpts...)
 	if err != nil {
 		return err
 	}
 
 	gr.DrawBorder()
+	if tm.grid.lines {
+		gr.DrawGrid()
+	}
 
-	for c := 0; c < tm.cols; c++ {
-		for r := 0; r < tm.rows; r++ {
-			// Grab the tile index
-			pos := r*tm.cols + c
-			if pos >= len(tm.layout) {
-				return fmt.Errorf("invalid index [%d] with a grid length of %d", pos, len(tm.layout))
-			}
+	if err := tm.renderGroups(gr); err != nil {
+		return err
+	}
+
+	rules := tm.autotiles(repo)
+	for _, ly := range tm.layers {
+		if !ly.visible {
+			continue
+		}
 
-			idx := tm.layout[pos]
-			if idx <= 0 {
-				continue
+		if err := tm.renderLayer(gr, repo, rules, ly); err != nil {
+			return err
+		}
+	}
+
+	if err := tm.renderConnections(gr, repo); err != nil {
+		return err
+	}
+
+	if err := tm.renderGroupTitles(gr); err != nil {
+		return err
+	}
+
+	for _, lb := range tm.labels {
+		if err := gr.DrawLabel(lb.text, lb.row, lb.col, lb.drawOptions()...); err != nil {
+			return err
+		}
+	}
+
+	if tm.grid.coords {
+		gr.DrawCoords()
+	}
+
+	wopts, err := tm.watermark.drawOptions(repo)
+	if err != nil {
+		return err
+	}
+	gr.DrawWatermark(wopts...)
+
+	return gr.Encode(wr)
+}
+
+// tileRect returns the cell width and height: tile_width
+// and tile_height (if any) take precedence over tile_size.
+func (tm *TileMap) tileRect() (w, h int) {
+	w, h = tm.tileSize, tm.tileSize
+	if tm.tileWidth > 0 {
+		w = tm.tileWidth
+	}
+	if tm.tileHeight > 0 {
+		h = tm.tileHeight
+	}
+	return w, h
+}
+
+// tilesets returns the preloaded tilesets or
+// fetches all the tilesets in the atlas list.
+func (tm *TileMap) tilesets() ([]*tileset.Tileset, error) {
+	if tm.repo != nil {
+		return tm.repo, nil
+	}
+
+	return tileset.Load(tm.atlasList...)
+}
+
+// refOf returns the mapping entry of a non empty cell:
+// cells can reference an index, an alias or a tile id.
+func (tm *TileMap) refOf(el cell) (tileRef, error) {
+	if el.name != "" {
+		if ref, ok := tm.aliases[el.name]; ok {
+			return ref, nil
+		}
+		return tileRef{id: el.name}, nil
+	}
+
+	ref, ok := tm.mapping[el.index]
+	if !ok {
+		return tileRef{}, fmt.Errorf("tile with index: %d not found in mapping", el.index)
+	}
+
+	return ref, nil
+}
+
+// renderLayer draws all the tiles of the layer onto the grid.
+// Multi-cell tiles reserve all the covered cells, any other
+// tile placed on a reserved cell is reported as an error.
+func (tm *TileMap) renderLayer(gr *grid.Grid, repo []*tileset.Tileset, rules map[string]*tileset.Autotile, ly *layer) error {
+	// keeps track of the anchor cell of the tile covering each position
+	occupied := make(map[int]int)
+
+	for _, pos := range tm.drawOrder() {
+		r, c := pos/tm.cols, pos%tm.cols
+
+		// Grab the tile index
+		if pos >= len(ly.layout) {
+			return fmt.Errorf("layer %q: invalid index [%d] with a grid length of %d", ly.name, pos, len(ly.layout))
+		}
+
+		el := ly.layout[pos]
+		if el.empty() {
+			continue
+		}
+
+		// Find the image for the tile id
+		ref, err := tm.refOf(el)
+		if err != nil {
+			return err
+		}
+
+		fitMode, filter := tm.fit, tm.filter
+		if ref.fit != "" {
+			fitMode = ref.fit
+		}
+		if ref.filter != "" {
+			filter = ref.filter
+		}
+		if el.fit != "" {
+			fitMode = el.fit
+		}
+		if el.filter != "" {
+			filter = el.filter
+		}
+
+		// autotiles pick the tile according to the neighbours
+		if ref.autotile != "" {
+			if ref, el, err = tm.autotile(ly, r, c, ref.autotile, rules); err != nil {
+				return fmt.Errorf("layer %q: %s", ly.name, err)
 			}
+		}
+
+		img, tile, err := findTileByID(repo, ref.id)
+		if err != nil {
+			return err
+		}
 
-			// Find the image for the tile id
-			id, ok := tm.mapping[idx]
-			if !ok {
-				return fmt.Errorf("tile with index: %d not found in mapping", idx)
+		cols, rows := spanOf(ref, tile)
+		if r+rows > tm.rows || c+cols > tm.cols {
+			return fmt.Errorf("layer %q: tile %q at cell (%d, %d) with span %dx%d is out of bounds",
+				ly.name, ref.id, r, c, cols, rows)
+		}
+
+		for i := r; i < r+rows; i++ {
+			for j := c; j < c+cols; j++ {
+				if owner, ok := occupied[i*tm.cols+j]; ok {
+					return fmt.Errorf("layer %q: tile %q at cell (%d, %d) overlaps tile at cell (%d, %d)",
+						ly.name, ref.id, r, c, owner/tm.cols, owner%tm.cols)
+				}
+				occupied[i*tm.cols+j] = pos
 			}
+		}
+
+		opts := append(el.drawOptions(),
+			grid.Span(rows, cols),
+			grid.Opacity(ly.opacity),
+			grid.Offset(ly.offsetX, ly.offsetY),
+			grid.Fit(fitMode),
+			grid.Filter(filter))
+
+		if err := gr.DrawImage(img, r, c, opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-			img, err := findImageByID(repo, id)
-			if err != nil {
-				return err
+// drawOrder returns the cell positions in drawing order: column by
+// column, or back to front for isometric maps (so that taller tiles
+// overlap the ones behind them).
+func (tm *TileMap) drawOrder() []int {
+	res := make([]int, 0, tm.rows*tm.cols)
+
+	if tm.gridType != grid.TypeIsometric {
+		for c := 0; c < tm.cols; c++ {
+			for r := 0; r < tm.rows; r++ {
+				res = append(res, r*tm.cols+c)
 			}
+		}
+		return res
+	}
 
-			gr.DrawImage(img, r, c)
+	for r := 0; r < tm.rows; r++ {
+		for c := 0; c < tm.cols; c++ {
+			res = append(res, r*tm.cols+c)
 		}
 	}
 
-	gr.DrawWatermark()
+	// the depth of diamond cells is the sum of row and column
+	if tm.isoProjection != grid.IsoStaggered {
+		sort.SliceStable(res, func(i, j int) bool {
+			return res[i]/tm.cols+res[i]%tm.cols < res[j]/tm.cols+res[j]%tm.cols
+		})
+	}
 
-	return gr.EncodePNG(wr)
+	return res
 }
 
 // UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
 func (tm *TileMap) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	aux := struct {
-		Cols      int            `yaml:"cols"`
-		Rows      int            `yaml:"rows"`
-		TileSize  int            `yaml:"tile_size"`
-		Margin    int            `yaml:"margin"`
-		BgColor   string         `yaml:"bg_color"`
-		Layout    string         `yaml:"layout"`
-		Watermark string         `yaml:"watermark"`
-		Mapping   map[int]string `yaml:"mapping"`
-		AtlasList []string       `yaml:"atlas_list"`
+		Cols      int                          `yaml:"cols"`
+		Rows      int                          `yaml:"rows"`
+		TileSize  int                          `yaml:"tile_size"`
+		TileW     int                          `yaml:"tile_width"`
+		TileH     int                          `yaml:"tile_height"`
+		Margin    int                          `yaml:"margin"`
+		BgColor   string                       `yaml:"bg_color"`
+		Layout    layoutSpec                   `yaml:"layout"`
+		Watermark watermark                    `yaml:"watermark"`
+		Mapping   map[int]tileRef              `yaml:"mapping"`
+		Aliases   map[string]tileRef           `yaml:"aliases"`
+		Legend    map[string]tileRef           `yaml:"legend"`
+		AtlasList []string                     `yaml:"atlas_list"`
+		Layers    []*layer                     `yaml:"layers"`
+		Font      string                       `yaml:"font"`
+		Cells     map[string][2]int            `yaml:"cells"`
+		Labels    map[string]*label            `yaml:"labels"`
+		Autotiles map[string]*tileset.Autotile `yaml:"autotiles"`
+		GridType  string                       `yaml:"grid_type"`
+		HexOrient string                       `yaml:"hex_orientation"`
+		HexOffset string                       `yaml:"hex_offset"`
+		IsoProj   string                       `yaml:"iso_projection"`
+		IsoRatio  float64                      `yaml:"iso_ratio"`
+		Fit       string                       `yaml:"fit"`
+		Filter    string                       `yaml:"filter"`
+		Grid      gridSpec                     `yaml:"grid"`
+		Connects  []*connection                `yaml:"connections"`
+		Groups    []*group                     `yaml:"groups"`
 	}{}
 
+	tm.issues = nil
+	fail := func(err error, locate func(src *outlineNode) int) {
+		tm.issues = append(tm.issues, decodeIssue{err: err, locate: locate})
+	}
+
 	err := unmarshal(&aux)
-	if err != nil {
+	if te, ok := err.(*yaml.TypeError); ok && tm.lenient {
+		// the other fields are decoded anyway
+		for _, msg := range te.Errors {
+			fail(fmt.Errorf("%s", msg), nil)
+		}
+	} else if err != nil {
 		return err
 	}
 
 	tm.cols = aux.Cols
 	tm.rows = aux.Rows
 	tm.tileSize = aux.TileSize
+	tm.tileWidth = aux.TileW
+	tm.tileHeight = aux.TileH
+	if tm.tileSize == 0 {
+		tm.tileSize = maxInt(aux.TileW, aux.TileH)
+	}
 	tm.margin = aux.Margin
 	tm.bgColor = aux.BgColor
 	tm.watermark = aux.Watermark
-	tm.mapping = make(map[int]string)
+	tm.mapping = make(map[int]tileRef)
 	for k, v := range aux.Mapping {
 		tm.mapping[k] = v
 	}
 
+	tm.aliases = make(map[string]tileRef)
+	for k, v := range aux.Aliases {
+		tm.aliases[k] = v
+	}
+
+	// legend characters are aliases too
+	for k, v := range aux.Legend {
+		if len([]rune(k)) != 1 {
+			fail(fmt.Errorf("legend key %q must be a single character", k), keyLine("legend", k))
+			continue
+		}
+		tm.aliases[k] = v
+	}
+
 	tm.atlasList = make([]string, len(aux.AtlasList))
 	for i, uri := range aux.AtlasList {
 		tm.atlasList[i] = uri
 	}
 
-	layout := strings.Split(strings.Replace(aux.Layout, " ", ",", -1), ",")
+	// the top level layout, if any, is the bottom layer
+	tm.layers = []*layer{}
+	if !aux.Layout.empty() {
+		tm.layers = append(tm.layers, &layer{
+			name:    defaultLayerName,
+			spec:    aux.Layout,
+			opacity: 1,
+			visible: true,
+		})
+	}
+	tm.layers = append(tm.layers, aux.Layers...)
 
-	tm.layout = make([]int, len(layout))
-	for i := 0; i < len(layout); i++ {
-		num, err := strconv.Atoi(strings.TrimSpace(layout[i]))
-		if err != nil {
-			return err
+	for i, ly := range tm.layers {
+		if err := tm.resolveLayout(ly, aux.Legend); err != nil {
+			i, ly, cols := i, ly, tm.cols
+			fail(err, func(src *outlineNode) int {
+				return ly.errorLine(tm.layoutNode(src, i), err, cols)
+			})
+		}
+	}
+
+	tm.autotileRules = make(map[string]*tileset.Autotile)
+	for k, v := range aux.Autotiles {
+		tm.autotileRules[k] = v
+	}
+
+	tm.gridType = strings.ToLower(aux.GridType)
+	switch tm.gridType {
+	case "", grid.TypeSquare, grid.TypeHex, grid.TypeIsometric:
+	default:
+		fail(fmt.Errorf("unsupported grid_type %q", aux.GridType), keyLine("grid_type"))
+	}
+
+	tm.hexOrientation = strings.ToLower(aux.HexOrient)
+	switch tm.hexOrientation {
+	case "", grid.HexPointy, grid.HexFlat:
+	default:
+		fail(fmt.Errorf("invalid hex_orientation %q", aux.HexOrient), keyLine("hex_orientation"))
+	}
+
+	tm.hexOffset = strings.ToLower(aux.HexOffset)
+	switch tm.hexOffset {
+	case "", grid.OffsetOdd, grid.OffsetEven:
+	default:
+		fail(fmt.Errorf("invalid hex_offset %q", aux.HexOffset), keyLine("hex_offset"))
+	}
+
+	tm.isoProjection = strings.ToLower(aux.IsoProj)
+	switch tm.isoProjection {
+	case "", grid.IsoDiamond, grid.IsoStaggered:
+	default:
+		fail(fmt.Errorf("invalid iso_projection %q", aux.IsoProj), keyLine("iso_projection"))
+	}
+
+	tm.isoRatio = aux.IsoRatio
+	if tm.isoRatio < 0 || tm.isoRatio > 1 {
+		fail(fmt.Errorf("iso_ratio must be between 0 and 1"), keyLine("iso_ratio"))
+	}
+
+	tm.fit = strings.ToLower(aux.Fit)
+	tm.filter = strings.ToLower(aux.Filter)
+	if err := grid.CheckFit(tm.fit, tm.filter); err != nil {
+		fail(err, func(src *outlineNode) int {
+			if grid.CheckFit(tm.fit, "") != nil {
+				return src.find("fit").lineOf()
+			}
+			return src.find("filter").lineOf()
+		})
+	}
+
+	tm.grid = aux.Grid
+
+	tm.font = aux.Font
+	tm.names = make(map[string][2]int)
+	for k, v := range aux.Cells {
+		tm.names[k] = v
+	}
+
+	tm.labels, err = resolveLabels(aux.Labels, tm.names)
+	if err != nil {
+		fail(err, keyLine("labels"))
+	}
+
+	tm.connections = aux.Connects
+	if err := resolveConnections(tm.connections, tm.names); err != nil {
+		fail(err, keyLine("connections"))
+	}
+
+	tm.groups = aux.Groups
+	if err := resolveGroups(tm.groups, nil, tm.names); err != nil {
+		fail(err, keyLine("groups"))
+	}
+
+	if len(tm.issues) > 0 && !tm.lenient {
+		return tm.issues[0].err
+	}
+
+	return nil
+}
+
+// resolveLayout decodes the layer layout; ASCII art layouts
+// set the tilemap columns and rows (if not specified) or
+// must match them.
+func (tm *TileMap) resolveLayout(ly *layer, legend map[string]tileRef) error {
+	cells, cols, rows, err := ly.spec.resolve(legend)
+	if err != nil {
+		if le, ok := err.(*layoutError); ok {
+			return &layoutError{row: le.row, pos: le.pos, err: fmt.Errorf("layer %q: %s", ly.name, err)}
 		}
-		tm.layout[i] = num
+		return fmt.Errorf("layer %q: %s", ly.name, err)
+	}
+	ly.layout = cells
+
+	if cols == 0 || rows == 0 {
+		return nil
+	}
+
+	if tm.cols == 0 && tm.rows == 0 {
+		tm.cols, tm.rows = cols, rows
+	}
+
+	if cols != tm.cols || rows != tm.rows {
+		return fmt.Errorf("layer %q: layout size is %dx%d, expected %dx%d", ly.name, cols, rows, tm.cols, tm.rows)
 	}
 
 	return nil
 }
 
+// Load fetches the tilemap at the specified uri;
+// Tiled maps (.tmx) are converted on the fly.
 func Load(uri string) (TileMap, error) {
+	if strings.EqualFold(filepath.Ext(uri), ".tmx") {
+		return LoadTMX(uri)
+	}
+
 	dat, err := data.Fetch(uri, -1)
 	if err != nil {
 		return TileMap{}, err
@@ -137,12 +547,47 @@ func Load(uri string) (TileMap, error) {
 	return res, nil
 }
 
-func findImageByID(repo []*tileset.Tileset, id string) (image.Image, error) {
+// loadFont fetches and parses the TrueType font at
+// the specified uri; returns nil if no uri is specified.
+func loadFont(uri string) (*truetype.Font, error) {
+	if uri == "" {
+		return nil, nil
+	}
+
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	return truetype.Parse(dat)
+}
+
+func findTileByID(repo []*tileset.Tileset, id string) (image.Image, tileset.Tile, error) {
 	for _, el := range repo {
 		if tile, ok := el.Get(id); ok {
-			return el.Image(tile)
+			img, err := el.Image(tile)
+			return img, tile, err
 		}
 	}
 
-	return nil, fmt.Errorf("tile with id: %s not found", id)
+	return nil, tileset.Tile{}, fmt.Errorf("tile with id: %s not found", id)
+}
+
+// spanOf returns the number of cells covered by the tile;
+// the mapping settings take precedence over the tileset ones.
+func spanOf(ref tileRef, tile tileset.Tile) (cols, rows int) {
+	cols, rows = 1, 1
+	if tile.SpanCols > 0 {
+		cols = tile.SpanCols
+	}
+	if tile.SpanRows > 0 {
+		rows = tile.SpanRows
+	}
+	if ref.cols > 0 {
+		cols = ref.cols
+	}
+	if ref.rows > 0 {
+		rows = ref.rows
+	}
+	return cols, rows
 }
@@ -1,43 +1,690 @@
 package tilemap
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/png"
-	"os"
+	"strings"
 	"testing"
 
+	"github.com/lucasepe/tiles/grid"
 	"github.com/lucasepe/tiles/tileset"
+	"gopkg.in/yaml.v2"
 )
 
 func TestFetchFromURI(t *testing.T) {
-	tm, err := Load("../examples/demo.yml")
+	tm, err := Load("../examples/tilemap_demo_1.yml")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	ts, err := tileset.Load(tm.atlasList...)
+	var buf bytes.Buffer
+	if err := tm.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	img, err := tm.Render(ts)
+	if got, want := img.Bounds().Dx(), 4*64+2*16; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestUnmarshalLayers(t *testing.T) {
+	src := `
+cols: 2
+rows: 2
+tile_size: 32
+layout: 1,0,0,1
+layers:
+  - name: overlay
+    opacity: 0.5
+    offset_x: 4
+    layout: 0,2,2,0
+  - name: hidden
+    visible: false
+    layout: 3,3,3,3
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(tm.layers), 3; got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	tests := []struct {
+		name    string
+		opacity float64
+		visible bool
+		offsetX int
+		layout  []int
+	}{
+		{defaultLayerName, 1, true, 0, []int{1, 0, 0, 1}},
+		{"overlay", 0.5, true, 4, []int{0, 2, 2, 0}},
+		{"hidden", 1, false, 0, []int{3, 3, 3, 3}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ly := tm.layers[i]
+			if ly.name != tt.name {
+				t.Errorf("got [%v] want [%v]", ly.name, tt.name)
+			}
+			if ly.opacity != tt.opacity {
+				t.Errorf("got [%v] want [%v]", ly.opacity, tt.opacity)
+			}
+			if ly.visible != tt.visible {
+				t.Errorf("got [%v] want [%v]", ly.visible, tt.visible)
+			}
+			if ly.offsetX != tt.offsetX {
+				t.Errorf("got [%v] want [%v]", ly.offsetX, tt.offsetX)
+			}
+			for j, want := range tt.layout {
+				if got := ly.layout[j].index; got != want {
+					t.Errorf("got [%v] want [%v]", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	tests := []struct {
+		src  string
+		want cell
+		err  string
+	}{
+		{"30", cell{index: 30}, ""},
+		{"30:r90", cell{index: 30, rotation: 90}, ""},
+		{"11:fh", cell{index: 11, flipH: true}, ""},
+		{"20:fv:r270", cell{index: 20, rotation: 270, flipV: true}, ""},
+		{"7:cover:nearest", cell{index: 7, fit: "cover", filter: "nearest"}, ""},
+		{"7:Upscale-Nearest:r90", cell{index: 7, rotation: 90, fit: "upscale-nearest"}, ""},
+		{"20:x", cell{}, `invalid transform "x" in cell "20:x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			got, err := parseCell(tt.src)
+			if tt.err != "" && err == nil {
+				t.Fatalf("expected error [%v]", tt.err)
+			}
+			if err != nil && err.Error() != tt.err {
+				t.Errorf("got [%v] want [%v]", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalMapping(t *testing.T) {
+	src := `
+mapping:
+  1: aws_lambda
+  2: { id: aws_vpc, span: 3x2 }
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		idx  int
+		want tileRef
+	}{
+		{1, tileRef{id: "aws_lambda"}},
+		{2, tileRef{id: "aws_vpc", cols: 3, rows: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want.id, func(t *testing.T) {
+			if got := tm.mapping[tt.idx]; got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLayerOverlap(t *testing.T) {
+	tests := []struct {
+		layout string
+		want   string
+	}{
+		{"2,0,0,0,0,0,1,0,0", ""},
+		{"2,0,0,0,1,0,0,0,0", `layer "default": tile "b" at cell (1, 1) overlaps tile at cell (0, 0)`},
+		{"0,0,0,0,0,0,0,0,2", `layer "default": tile "a" at cell (2, 2) with span 2x2 is out of bounds`},
+	}
+
+	repo := []*tileset.Tileset{testTileset(t)}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			layout, err := parseLayout(tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tm := TileMap{
+				cols: 3, rows: 3, tileSize: 8,
+				mapping: map[int]tileRef{
+					1: {id: "b"},
+					2: {id: "a", cols: 2, rows: 2},
+				},
+			}
+
+			gr, err := grid.NewGrid(tm.rows, tm.cols, tm.tileSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tm.renderLayer(gr, repo, nil, &layer{name: defaultLayerName, layout: layout, opacity: 1})
+			if got := fmt.Sprint(err); err != nil && got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+			if err == nil && tt.want != "" {
+				t.Errorf("got [nil] want [%v]", tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCellFit(t *testing.T) {
+	tests := []struct {
+		layout string
+		black  bool
+	}{
+		// the map fit scales the 8x8 tile to the whole cell
+		{"1", true},
+		// the cell suffix draws it at its native size
+		{"1:none", false},
+	}
+
+	repo := []*tileset.Tileset{testTileset(t)}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			layout, err := parseLayout(tt.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tm := TileMap{
+				cols: 1, rows: 1, tileSize: 32, fit: grid.FitContain,
+				mapping: map[int]tileRef{1: {id: "a"}},
+			}
+
+			gr, err := grid.NewGrid(1, 1, 32, grid.Margin(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tm.renderLayer(gr, repo, nil, &layer{name: defaultLayerName, layout: layout, opacity: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			r, _, _, _ := gr.Context().Image().At(2, 2).RGBA()
+			if got := r == 0; got != tt.black {
+				t.Errorf("got [%v] want [%v]", got, tt.black)
+			}
+		})
+	}
+}
+
+func TestRenderOptionsPerCall(t *testing.T) {
+	tm := TileMap{
+		cols: 1, rows: 1, tileSize: 8,
+		repo: []*tileset.Tileset{testTileset(t)},
+	}
+
+	var buf bytes.Buffer
+	if err := tm.Render(&buf, Format(grid.FormatSVG), Debug(true)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(buf.Bytes()), []byte("<")) {
+		t.Fatalf("expected an SVG image")
+	}
+
+	// a second call without options is back to the defaults
+	buf.Reset()
+	if err := tm.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(&buf); err != nil {
+		t.Errorf("expected a PNG image: %v", err)
+	}
+	if tm.format != "" || tm.grid.coords {
+		t.Errorf("the options must not change the tilemap")
+	}
+}
+
+// testTileset returns an in-memory tileset with two 8x8 tiles: 'a' and 'b'.
+func testTileset(t *testing.T) *tileset.Tileset {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	draw.Draw(img, image.Rect(0, 0, 8, 8), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return &tileset.Tileset{
+		Width: 16, Height: 8,
+		Data: base64.StdEncoding.EncodeToString(buf.Bytes()),
+		Tiles: []*tileset.Tile{
+			{ID: "a", MinX: 0, MinY: 0, MaxX: 8, MaxY: 8},
+			{ID: "b", MinX: 8, MinY: 0, MaxX: 16, MaxY: 8},
+		},
+	}
+}
+
+func TestUnmarshalLabels(t *testing.T) {
+	src := `
+cells:
+  orders-api: [1, 2]
+labels:
+  orders-api: { text: orders api, anchor: inside, wrap: true }
+  "0,1": redis-cache
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		row, col int
+		text     string
+		anchor   string
+		wrap     bool
+	}{
+		{0, 1, "redis-cache", "", false},
+		{1, 2, "orders api", "inside", true},
+	}
+
+	if got, want := len(tm.labels), len(tests); got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			lb := tm.labels[i]
+			if lb.row != tt.row || lb.col != tt.col {
+				t.Errorf("got [%d,%d] want [%d,%d]", lb.row, lb.col, tt.row, tt.col)
+			}
+			if lb.text != tt.text {
+				t.Errorf("got [%v] want [%v]", lb.text, tt.text)
+			}
+			if lb.anchor != tt.anchor {
+				t.Errorf("got [%v] want [%v]", lb.anchor, tt.anchor)
+			}
+			if lb.wrap != tt.wrap {
+				t.Errorf("got [%v] want [%v]", lb.wrap, tt.wrap)
+			}
+		})
+	}
+}
+
+func TestUnmarshalNamedLayout(t *testing.T) {
+	src := `
+mapping:
+  1: aws_lambda
+aliases:
+  db: { id: aws_rds_mysql_instance, span: 2x1 }
+  "-": link_horizontal
+layers:
+  - name: lists
+    layout:
+      - [., aws_lambda, _]
+      - [db, "-:r90", 1]
+  - name: strings
+    layout:
+      - ". aws_lambda _"
+      - db -:r90 1
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []cell{
+		{}, {name: "aws_lambda"}, {},
+		{name: "db"}, {name: "-", rotation: 90}, {index: 1},
+	}
+
+	refs := []tileRef{
+		{id: "aws_rds_mysql_instance", cols: 2, rows: 1},
+		{id: "link_horizontal"},
+		{id: "aws_lambda"},
+	}
+
+	for _, ly := range tm.layers {
+		t.Run(ly.name, func(t *testing.T) {
+			if len(ly.layout) != len(want) {
+				t.Fatalf("got [%v] want [%v]", ly.layout, want)
+			}
+			for i := range want {
+				if ly.layout[i] != want[i] {
+					t.Errorf("got [%v] want [%v]", ly.layout[i], want[i])
+				}
+			}
+			for i, el := range ly.layout[3:] {
+				got, err := tm.refOf(el)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != refs[i] {
+					t.Errorf("got [%v] want [%v]", got, refs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLayoutErrorsHaveLayerName(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"layers: [{ name: top, layout: \"1,x\" }]", `layer "top": `},
+		{"layers: [{ name: top, layout: [[aws_lambda, \"1:r45\"]] }]", `layer "top": invalid transform "r45"`},
+		{"layers: [{ name: top, layout: { a: 1 } }]", `layer "top": layout must be`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			var tm TileMap
+			err := yaml.Unmarshal([]byte(tt.src), &tm)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got [%v] want [%v...]", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHex(t *testing.T) {
+	src := `
+cols: 3
+rows: 2
+tile_size: 8
+margin: 0
+grid_type: hex
+hex_orientation: flat
+hex_offset: even
+mapping:
+  1: a
+layout: 1,0,1,0,1,0
+`
+	tm := TileMap{}
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+	tm.repo = []*tileset.Tileset{testTileset(t)}
+
+	var buf bytes.Buffer
+	if err := tm.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	savePNG(img, "delme.png")
+	// flat hexagons: 2r + 2*1.5r wide (r = 8/sqrt(3)), 2.5 cells high
+	if got, want := img.Bounds().Size(), image.Pt(24, 20); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+
+	if err := yaml.Unmarshal([]byte("grid_type: triangle"), &tm); err == nil {
+		t.Errorf("expected an error for an unsupported grid type")
+	}
 }
 
-func savePNG(im image.Image, filename string) error {
-	fp, err := os.Create(filename)
+func TestDrawOrder(t *testing.T) {
+	tests := []struct {
+		tm   TileMap
+		want []int
+	}{
+		{TileMap{cols: 3, rows: 2}, []int{0, 3, 1, 4, 2, 5}},
+		{TileMap{cols: 3, rows: 2, gridType: grid.TypeIsometric}, []int{0, 1, 3, 2, 4, 5}},
+		{TileMap{cols: 3, rows: 2, gridType: grid.TypeIsometric, isoProjection: grid.IsoStaggered}, []int{0, 1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			got := tt.tm.drawOrder()
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalTileRect(t *testing.T) {
+	src := `
+cols: 2
+rows: 1
+tile_width: 32
+tile_height: 48
+mapping:
+  1: a
+layout: 1,1
+`
+	tm := TileMap{}
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	if w, h := tm.tileRect(); w != 32 || h != 48 {
+		t.Errorf("got [%dx%d] want [32x48]", w, h)
+	}
+
+	tm.repo = []*tileset.Tileset{testTileset(t)}
+	tm.margin = 0
+
+	var buf bytes.Buffer
+	if err := tm.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
 	if err != nil {
-		return err
+		t.Fatal(err)
+	}
+
+	if got, want := img.Bounds().Size(), image.Pt(64, 48); got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestUnmarshalFit(t *testing.T) {
+	src := `
+fit: contain
+filter: Linear
+mapping:
+  1: { id: a, fit: upscale-nearest }
+`
+	tm := TileMap{}
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
 	}
-	defer fp.Close()
 
-	enc := png.Encoder{
-		CompressionLevel: png.BestSpeed,
+	got := []string{tm.fit, tm.filter, tm.mapping[1].fit}
+	want := []string{grid.FitContain, "linear", grid.FitUpscaleNearest}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got [%v] want [%v]", got, want)
 	}
 
-	return enc.Encode(fp, im)
+	if err := yaml.Unmarshal([]byte("fit: zoom"), &tm); err == nil {
+		t.Errorf("expected an error for an invalid fit mode")
+	}
+}
+
+func TestUnmarshalGrid(t *testing.T) {
+	src := `
+grid:
+  lines: true
+  line_color: "#ff0000"
+  line_dashes: 4
+  border_width: 2
+`
+	tm := TileMap{}
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	want := gridSpec{lines: true, lineColor: "#ff0000", lineDashes: 4, borderWidth: 2}
+	if tm.grid != want {
+		t.Errorf("got [%v] want [%v]", tm.grid, want)
+	}
+
+	Debug(true)(&tm)
+	if !tm.grid.coords {
+		t.Errorf("expected the coordinates overlay to be on")
+	}
+}
+
+func TestUnmarshalWatermark(t *testing.T) {
+	tests := []struct {
+		src  string
+		want watermark
+	}{
+		{`watermark: Draft`, watermark{text: "Draft", opacity: 1}},
+		{
+			`watermark: { text: DRAFT, position: Top-Left, angle: -45, tiled: true }`,
+			watermark{text: "DRAFT", position: grid.PositionTopLeft, angle: -45, tiled: true, opacity: 1},
+		},
+		{
+			`watermark: { tile: logo, opacity: 0.3 }`,
+			watermark{tile: "logo", opacity: 0.3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			tm := TileMap{}
+			if err := yaml.Unmarshal([]byte(tt.src), &tm); err != nil {
+				t.Fatal(err)
+			}
+			if tm.watermark != tt.want {
+				t.Errorf("got [%v] want [%v]", tm.watermark, tt.want)
+			}
+		})
+	}
+
+	tm := TileMap{}
+	if err := yaml.Unmarshal([]byte(`watermark: { position: middle }`), &tm); err == nil {
+		t.Errorf("expected an error for an invalid position")
+	}
+}
+
+func TestUnmarshalConnections(t *testing.T) {
+	src := `
+cols: 4
+rows: 3
+cells:
+  db: [2, 3]
+connections:
+  - { from: [0, 0], to: db, arrow: both, label: SQL }
+  - { from: "1,2", to: [0, 3], dashes: 4 }
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to [2]int
+		arrow    string
+	}{
+		{[2]int{0, 0}, [2]int{2, 3}, "both"},
+		{[2]int{1, 2}, [2]int{0, 3}, ""},
+	}
+
+	if got, want := len(tm.connections), len(tests); got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i, tt := range tests {
+		cn := tm.connections[i]
+		if got := [2]int{cn.from.row, cn.from.col}; got != tt.from {
+			t.Errorf("got [%v] want [%v]", got, tt.from)
+		}
+		if got := [2]int{cn.to.row, cn.to.col}; got != tt.to {
+			t.Errorf("got [%v] want [%v]", got, tt.to)
+		}
+		if cn.arrow != tt.arrow {
+			t.Errorf("got [%v] want [%v]", cn.arrow, tt.arrow)
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte("connections: [{ from: [0, 0] }]"), &tm); err == nil {
+		t.Errorf("got [nil] want an error for a missing 'to' cell")
+	}
+}
+
+func TestUnmarshalGroups(t *testing.T) {
+	src := `
+cols: 4
+rows: 3
+cells:
+  web: [2, 3]
+groups:
+  - title: VPC
+    from: [0, 0]
+    to: web
+    border_dashes: 6
+    groups:
+      - { title: subnet, from: "1,1", to: [2, 2], fill_color: "#7aa11622" }
+`
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(tm.groups), 1; got != want {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	tests := []struct {
+		gp   *group
+		want [4]int
+	}{
+		{tm.groups[0], [4]int{0, 0, 2, 3}},
+		{tm.groups[0].groups[0], [4]int{1, 1, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.gp.title, func(t *testing.T) {
+			r0, c0, r1, c1 := tt.gp.bounds()
+			if got := [4]int{r0, c0, r1, c1}; got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+
+	src = `
+groups:
+  - title: VPC
+    from: [0, 0]
+    to: [1, 1]
+    groups:
+      - { title: subnet, from: [1, 1], to: [2, 2] }
+`
+	if err := yaml.Unmarshal([]byte(src), &tm); err == nil {
+		t.Errorf("got [nil] want an error for a nested group outside its parent")
+	}
 }
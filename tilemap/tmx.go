@@ -0,0 +1,784 @@
+package tilemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/data"
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tileset"
+)
+
+// Tiled global tile id flags.
+const (
+	tmxFlipH uint32 = 0x80000000
+	tmxFlipV uint32 = 0x40000000
+	tmxFlipD uint32 = 0x20000000
+	tmxFlags uint32 = 0xF0000000
+)
+
+// tmxMap is the root element of a Tiled map (.tmx) file.
+type tmxMap struct {
+	XMLName         xml.Name     `xml:"map"`
+	Version         string       `xml:"version,attr"`
+	TiledVersion    string       `xml:"tiledversion,attr,omitempty"`
+	Orientation     string       `xml:"orientation,attr"`
+	RenderOrder     string       `xml:"renderorder,attr,omitempty"`
+	Width           int          `xml:"width,attr"`
+	Height          int          `xml:"height,attr"`
+	TileWidth       int          `xml:"tilewidth,attr"`
+	TileHeight      int          `xml:"tileheight,attr"`
+	Infinite        int          `xml:"infinite,attr"`
+	HexSideLength   int          `xml:"hexsidelength,attr,omitempty"`
+	StaggerAxis     string       `xml:"staggeraxis,attr,omitempty"`
+	StaggerIndex    string       `xml:"staggerindex,attr,omitempty"`
+	BackgroundColor string       `xml:"backgroundcolor,attr,omitempty"`
+	NextLayerID     int          `xml:"nextlayerid,attr,omitempty"`
+	NextObjectID    int          `xml:"nextobjectid,attr,omitempty"`
+	Tilesets        []tmxTileset `xml:"tileset"`
+	Layers          []tmxLayer   `xml:"layer"`
+}
+
+// tmxTileset is a tileset embedded in a map or
+// stored in an external tileset (.tsx) file.
+type tmxTileset struct {
+	XMLName      xml.Name  `xml:"tileset"`
+	FirstGID     uint32    `xml:"firstgid,attr,omitempty"`
+	Source       string    `xml:"source,attr,omitempty"`
+	Version      string    `xml:"version,attr,omitempty"`
+	TiledVersion string    `xml:"tiledversion,attr,omitempty"`
+	Name         string    `xml:"name,attr,omitempty"`
+	TileWidth    int       `xml:"tilewidth,attr,omitempty"`
+	TileHeight   int       `xml:"tileheight,attr,omitempty"`
+	Spacing      int       `xml:"spacing,attr,omitempty"`
+	Margin       int       `xml:"margin,attr,omitempty"`
+	TileCount    int       `xml:"tilecount,attr,omitempty"`
+	Columns      int       `xml:"columns,attr,omitempty"`
+	Image        *tmxImage `xml:"image"`
+	Tiles        []tmxTile `xml:"tile"`
+}
+
+type tmxImage struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
+}
+
+// tmxTile holds the per-tile settings; x, y, width and height
+// define the tile sub-rectangle in image collection tilesets.
+type tmxTile struct {
+	ID         int            `xml:"id,attr"`
+	X          int            `xml:"x,attr,omitempty"`
+	Y          int            `xml:"y,attr,omitempty"`
+	Width      int            `xml:"width,attr,omitempty"`
+	Height     int            `xml:"height,attr,omitempty"`
+	Properties *tmxProperties `xml:"properties"`
+	Image      *tmxImage      `xml:"image"`
+}
+
+type tmxProperties struct {
+	Property []tmxProperty `xml:"property"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type tmxLayer struct {
+	ID      int     `xml:"id,attr,omitempty"`
+	Name    string  `xml:"name,attr"`
+	Width   int     `xml:"width,attr"`
+	Height  int     `xml:"height,attr"`
+	Opacity string  `xml:"opacity,attr,omitempty"`
+	Visible string  `xml:"visible,attr,omitempty"`
+	OffsetX float64 `xml:"offsetx,attr,omitempty"`
+	OffsetY float64 `xml:"offsety,attr,omitempty"`
+	Data    tmxData `xml:"data"`
+}
+
+type tmxData struct {
+	Encoding    string        `xml:"encoding,attr,omitempty"`
+	Compression string        `xml:"compression,attr,omitempty"`
+	Tiles       []tmxDataTile `xml:"tile"`
+	Chunks      []xml.Name    `xml:"chunk"`
+	Content     string        `xml:",innerxml"`
+}
+
+type tmxDataTile struct {
+	GID uint32 `xml:"gid,attr"`
+}
+
+// LoadTMX fetches a Tiled map (orthogonal, hexagonal, isometric or
+// staggered with odd rows shifted) and converts it
+// to a tilemap; external tilesets (.tsx) and images are resolved
+// relative to the map location.
+func LoadTMX(uri string) (TileMap, error) {
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return TileMap{}, err
+	}
+
+	var src tmxMap
+	if err := xml.Unmarshal(dat, &src); err != nil {
+		return TileMap{}, err
+	}
+
+	switch src.Orientation {
+	case "orthogonal", "hexagonal", "isometric":
+	case "staggered":
+		if src.StaggerAxis == "x" || src.StaggerIndex == "even" {
+			return TileMap{}, fmt.Errorf("unsupported staggered map: only odd rows can be shifted")
+		}
+	default:
+		return TileMap{}, fmt.Errorf("unsupported map orientation: %s", src.Orientation)
+	}
+
+	if src.Infinite != 0 {
+		return TileMap{}, fmt.Errorf("infinite maps are not supported")
+	}
+
+	res := TileMap{
+		cols:     src.Width,
+		rows:     src.Height,
+		tileSize: maxInt(src.TileWidth, src.TileHeight),
+		bgColor:  tmxColor(src.BackgroundColor),
+		mapping:  make(map[int]tileRef),
+		names:    make(map[string][2]int),
+		layers:   []*layer{},
+		repo:     []*tileset.Tileset{},
+	}
+
+	if src.Orientation == "orthogonal" && src.TileWidth != src.TileHeight {
+		res.tileWidth, res.tileHeight = src.TileWidth, src.TileHeight
+	}
+
+	// Tiled staggers rows along the y axis (pointy hexagons)
+	// or columns along the x axis (flat hexagons)
+	if src.Orientation == "hexagonal" {
+		res.gridType = grid.TypeHex
+		res.hexOrientation = grid.HexPointy
+		res.hexOffset = src.StaggerIndex
+		res.tileSize = src.TileWidth
+		if src.StaggerAxis == "x" {
+			res.hexOrientation = grid.HexFlat
+			res.tileSize = src.TileHeight
+		}
+	}
+
+	if src.Orientation == "isometric" || src.Orientation == "staggered" {
+		res.gridType = grid.TypeIsometric
+		res.isoProjection = grid.IsoDiamond
+		if src.Orientation == "staggered" {
+			res.isoProjection = grid.IsoStaggered
+		}
+		res.tileSize = src.TileWidth
+		if src.TileWidth > 0 {
+			res.isoRatio = float64(src.TileHeight) / float64(src.TileWidth)
+		}
+	}
+
+	for _, el := range src.Tilesets {
+		base := uri
+		if el.Source != "" {
+			base = data.Resolve(uri, el.Source)
+			firstGID := el.FirstGID
+			if el, err = fetchTSX(base); err != nil {
+				return TileMap{}, err
+			}
+			el.FirstGID = firstGID
+		}
+
+		sets, err := decodeTMXTileset(base, el, res.mapping)
+		if err != nil {
+			return TileMap{}, err
+		}
+		res.repo = append(res.repo, sets...)
+	}
+
+	for _, el := range src.Layers {
+		ly, err := decodeTMXLayer(el, src.Width*src.Height)
+		if err != nil {
+			return TileMap{}, err
+		}
+		res.layers = append(res.layers, ly)
+	}
+
+	return res, nil
+}
+
+// ExportTMX writes the tilemap as a Tiled map named 'name'.tmx in the
+// specified directory; each tileset is exported as a .tsx file and a
+// .png image (using per-tile sub-rectangles, Tiled 1.9 or later).
+// Labels, spans and cell names have no Tiled equivalent and are ignored.
+func (tm *TileMap) ExportTMX(dir, name string) error {
+	repo, err := tm.tilesets()
+	if err != nil {
+		return err
+	}
+
+	tileWidth, tileHeight := tm.tileRect()
+
+	res := tmxMap{
+		Version:      "1.10",
+		TiledVersion: "1.10.0",
+		Orientation:  "orthogonal",
+		RenderOrder:  "right-down",
+		Width:        tm.cols,
+		Height:       tm.rows,
+		TileWidth:    tileWidth,
+		TileHeight:   tileHeight,
+		NextLayerID:  len(tm.layers) + 1,
+		NextObjectID: 1,
+	}
+	if tm.gridType == grid.TypeHex {
+		// the tile size is the distance between two opposite sides
+		side := int(math.Round(float64(tm.tileSize) / math.Sqrt(3)))
+		res.Orientation = "hexagonal"
+		res.HexSideLength = side
+		res.StaggerAxis, res.TileHeight = "y", 2*side
+		if tm.hexOrientation == grid.HexFlat {
+			res.StaggerAxis, res.TileWidth, res.TileHeight = "x", 2*side, tm.tileSize
+		}
+		res.StaggerIndex = grid.OffsetOdd
+		if tm.hexOffset == grid.OffsetEven {
+			res.StaggerIndex = grid.OffsetEven
+		}
+	}
+	if tm.gridType == grid.TypeIsometric {
+		ratio := tm.isoRatio
+		if ratio == 0 {
+			ratio = 0.5
+		}
+		res.Orientation = "isometric"
+		if tm.isoProjection == grid.IsoStaggered {
+			res.Orientation = "staggered"
+			res.StaggerAxis, res.StaggerIndex = "y", grid.OffsetOdd
+		}
+		res.TileHeight = int(math.Round(float64(tm.tileSize) * ratio))
+	}
+	if tm.bgColor != "" {
+		res.BackgroundColor = "#" + strings.TrimPrefix(tm.bgColor, "#")
+	}
+
+	// global ids of the first tile of each tileset
+	firstGIDs := make([]uint32, len(repo))
+	names := map[string]bool{}
+
+	gid := uint32(1)
+	for i, ts := range repo {
+		firstGIDs[i] = gid
+		gid += uint32(len(ts.Tiles))
+
+		tsName := strings.TrimSuffix(filepath.Base(ts.URI()), filepath.Ext(ts.URI()))
+		if tsName == "" || tsName == "." || names[tsName] {
+			tsName = fmt.Sprintf("%s_tileset_%d", name, i)
+		}
+		names[tsName] = true
+
+		if err := exportTSX(ts, dir, tsName); err != nil {
+			return err
+		}
+
+		res.Tilesets = append(res.Tilesets, tmxTileset{
+			FirstGID: firstGIDs[i],
+			Source:   tsName + ".tsx",
+		})
+	}
+
+	rules := tm.autotiles(repo)
+	for i, ly := range tm.layers {
+		el, err := tm.encodeTMXLayer(ly, repo, rules, firstGIDs)
+		if err != nil {
+			return err
+		}
+		el.ID = i + 1
+		res.Layers = append(res.Layers, el)
+	}
+
+	return writeXML(filepath.Join(dir, name+".tmx"), &res)
+}
+
+// fetchTSX loads an external tileset file.
+func fetchTSX(uri string) (tmxTileset, error) {
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return tmxTileset{}, err
+	}
+
+	var res tmxTileset
+	err = xml.Unmarshal(dat, &res)
+	return res, err
+}
+
+// decodeTMXTileset converts a Tiled tileset to one (or more, for
+// image collections) tile sets; the global id of each tile is
+// added to the mapping. Tile ids are taken from the 'id' custom
+// property, or else derived from the tileset name (or first global
+// id, if unnamed) and local id.
+func decodeTMXTileset(base string, src tmxTileset, mapping map[int]tileRef) ([]*tileset.Tileset, error) {
+	prefix := src.Name
+	if prefix == "" {
+		prefix = fmt.Sprintf("tileset%d", src.FirstGID)
+	}
+
+	tileID := func(localID int) string {
+		for _, el := range src.Tiles {
+			if el.ID == localID && el.Properties != nil {
+				for _, p := range el.Properties.Property {
+					if p.Name == "id" && p.Value != "" {
+						return p.Value
+					}
+				}
+			}
+		}
+		return fmt.Sprintf("%s_%d", prefix, localID)
+	}
+
+	// single image tileset
+	if src.Image != nil {
+		uri := data.Resolve(base, src.Image.Source)
+		img, err := fetchImage(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		b := img.Bounds()
+		cols := src.Columns
+		if cols <= 0 {
+			cols = (b.Dx() - 2*src.Margin + src.Spacing) / (src.TileWidth + src.Spacing)
+		}
+		count := src.TileCount
+		if count <= 0 {
+			count = cols * ((b.Dy() - 2*src.Margin + src.Spacing) / (src.TileHeight + src.Spacing))
+		}
+
+		tiles := make([]*tileset.Tile, count)
+		for i := 0; i < count; i++ {
+			x := src.Margin + (i%cols)*(src.TileWidth+src.Spacing)
+			y := src.Margin + (i/cols)*(src.TileHeight+src.Spacing)
+			tiles[i] = &tileset.Tile{
+				ID:   tileID(i),
+				MinX: x, MinY: y,
+				MaxX: x + src.TileWidth, MaxY: y + src.TileHeight,
+			}
+			mapping[int(src.FirstGID)+i] = tileRef{id: tiles[i].ID}
+		}
+
+		ts, err := tileset.FromImage(uri, img, tiles)
+		if err != nil {
+			return nil, err
+		}
+		return []*tileset.Tileset{ts}, nil
+	}
+
+	// image collection: tiles sharing the same image
+	// source are grouped in a single tile set
+	res := []*tileset.Tileset{}
+	bySource := map[string]*tileset.Tileset{}
+	for _, el := range src.Tiles {
+		if el.Image == nil {
+			continue
+		}
+
+		uri := data.Resolve(base, el.Image.Source)
+		ts, ok := bySource[uri]
+		if !ok {
+			img, err := fetchImage(uri)
+			if err != nil {
+				return nil, err
+			}
+
+			ts, err = tileset.FromImage(uri, img, []*tileset.Tile{})
+			if err != nil {
+				return nil, err
+			}
+			bySource[uri] = ts
+			res = append(res, ts)
+		}
+
+		w, h := el.Width, el.Height
+		if w <= 0 || h <= 0 {
+			w, h = ts.Width-el.X, ts.Height-el.Y
+		}
+
+		tile := &tileset.Tile{
+			ID:   tileID(el.ID),
+			MinX: el.X, MinY: el.Y,
+			MaxX: el.X + w, MaxY: el.Y + h,
+		}
+		ts.Tiles = append(ts.Tiles, tile)
+		mapping[int(src.FirstGID)+el.ID] = tileRef{id: tile.ID}
+	}
+
+	return res, nil
+}
+
+// decodeTMXLayer converts a Tiled tile layer.
+func decodeTMXLayer(src tmxLayer, size int) (*layer, error) {
+	gids, err := decodeTMXData(src.Data)
+	if err != nil {
+		return nil, fmt.Errorf("layer %q: %s", src.Name, err)
+	}
+
+	if len(gids) != size {
+		return nil, fmt.Errorf("layer %q: got %d tiles, expected %d", src.Name, len(gids), size)
+	}
+
+	res := &layer{
+		name:    src.Name,
+		layout:  make([]cell, len(gids)),
+		opacity: 1,
+		visible: src.Visible != "0",
+		offsetX: int(src.OffsetX),
+		offsetY: int(src.OffsetY),
+	}
+
+	if src.Opacity != "" {
+		if res.opacity, err = strconv.ParseFloat(src.Opacity, 64); err != nil {
+			return nil, fmt.Errorf("layer %q: invalid opacity %q", src.Name, src.Opacity)
+		}
+	}
+
+	for i, gid := range gids {
+		res.layout[i] = cellFromGID(gid)
+	}
+
+	return res, nil
+}
+
+// decodeTMXData decodes the global tile ids of a layer;
+// supports XML, CSV and base64 (plain, zlib or gzip) encodings.
+func decodeTMXData(src tmxData) ([]uint32, error) {
+	if len(src.Chunks) > 0 {
+		return nil, fmt.Errorf("chunked layer data is not supported")
+	}
+
+	switch src.Encoding {
+	case "":
+		res := make([]uint32, len(src.Tiles))
+		for i, el := range src.Tiles {
+			res[i] = el.GID
+		}
+		return res, nil
+
+	case "csv":
+		res := []uint32{}
+		for _, el := range strings.Split(src.Content, ",") {
+			el = strings.TrimSpace(el)
+			if el == "" {
+				continue
+			}
+			num, err := strconv.ParseUint(el, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, uint32(num))
+		}
+		return res, nil
+
+	case "base64":
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(src.Content))
+		if err != nil {
+			return nil, err
+		}
+
+		var rd io.Reader = bytes.NewReader(raw)
+		switch src.Compression {
+		case "":
+		case "zlib":
+			zr, err := zlib.NewReader(rd)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			rd = zr
+		case "gzip":
+			gr, err := gzip.NewReader(rd)
+			if err != nil {
+				return nil, err
+			}
+			defer gr.Close()
+			rd = gr
+		default:
+			return nil, fmt.Errorf("unsupported compression: %s", src.Compression)
+		}
+
+		if raw, err = ioutil.ReadAll(rd); err != nil {
+			return nil, err
+		}
+
+		res := make([]uint32, len(raw)/4)
+		for i := range res {
+			res[i] = binary.LittleEndian.Uint32(raw[i*4:])
+		}
+		return res, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", src.Encoding)
+	}
+}
+
+// encodeTMXLayer converts a layer to a Tiled CSV tile layer.
+func (tm *TileMap) encodeTMXLayer(ly *layer, repo []*tileset.Tileset, rules map[string]*tileset.Autotile, firstGIDs []uint32) (tmxLayer, error) {
+	res := tmxLayer{
+		Name:    ly.name,
+		Width:   tm.cols,
+		Height:  tm.rows,
+		OffsetX: float64(ly.offsetX),
+		OffsetY: float64(ly.offsetY),
+		Data:    tmxData{Encoding: "csv"},
+	}
+	if ly.opacity < 1 {
+		res.Opacity = strconv.FormatFloat(ly.opacity, 'f', -1, 64)
+	}
+	if !ly.visible {
+		res.Visible = "0"
+	}
+
+	size := tm.cols * tm.rows
+	if len(ly.layout) < size {
+		return tmxLayer{}, fmt.Errorf("layer %q: invalid index [%d] with a grid length of %d", ly.name, size-1, len(ly.layout))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n")
+	for i, el := range ly.layout[:size] {
+		gid, err := tm.gidOf(ly, i, el, repo, rules, firstGIDs)
+		if err != nil {
+			return tmxLayer{}, fmt.Errorf("layer %q: %s", ly.name, err)
+		}
+
+		sb.WriteString(strconv.FormatUint(uint64(gid), 10))
+		if i < size-1 {
+			sb.WriteString(",")
+		}
+		if (i+1)%tm.cols == 0 {
+			sb.WriteString("\n")
+		}
+	}
+	res.Data.Content = sb.String()
+
+	return res, nil
+}
+
+// gidOf returns the Tiled global id (with the flip flags)
+// of the cell at the specified position of the layer.
+func (tm *TileMap) gidOf(ly *layer, pos int, el cell, repo []*tileset.Tileset, rules map[string]*tileset.Autotile, firstGIDs []uint32) (uint32, error) {
+	if el.empty() {
+		return 0, nil
+	}
+
+	ref, err := tm.refOf(el)
+	if err != nil {
+		return 0, err
+	}
+
+	if ref.autotile != "" {
+		if ref, el, err = tm.autotile(ly, pos/tm.cols, pos%tm.cols, ref.autotile, rules); err != nil {
+			return 0, err
+		}
+	}
+
+	for i, ts := range repo {
+		for j, tile := range ts.Tiles {
+			if strings.EqualFold(tile.ID, ref.id) {
+				return (firstGIDs[i] + uint32(j)) | el.gidFlags(), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("tile with id: %s not found", ref.id)
+}
+
+// exportTSX writes the tile set image and the Tiled
+// tileset (as an image collection with sub-rectangles).
+func exportTSX(ts *tileset.Tileset, dir, name string) error {
+	img, err := ts.Atlas()
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.Create(filepath.Join(dir, name+".png"))
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	if err := png.Encode(fp, img); err != nil {
+		return err
+	}
+
+	res := tmxTileset{
+		Version:      "1.10",
+		TiledVersion: "1.10.0",
+		Name:         name,
+		TileCount:    len(ts.Tiles),
+		Tiles:        make([]tmxTile, len(ts.Tiles)),
+	}
+
+	for i, el := range ts.Tiles {
+		res.TileWidth = maxInt(res.TileWidth, el.MaxX-el.MinX)
+		res.TileHeight = maxInt(res.TileHeight, el.MaxY-el.MinY)
+		res.Tiles[i] = tmxTile{
+			ID: i,
+			X:  el.MinX, Y: el.MinY,
+			Width: el.MaxX - el.MinX, Height: el.MaxY - el.MinY,
+			Properties: &tmxProperties{
+				Property: []tmxProperty{{Name: "id", Value: el.ID}},
+			},
+			Image: &tmxImage{
+				Source: name + ".png",
+				Width:  ts.Width, Height: ts.Height,
+			},
+		}
+	}
+
+	return writeXML(filepath.Join(dir, name+".tsx"), &res)
+}
+
+// writeXML writes the indented XML document to the specified file.
+func writeXML(filename string, v interface{}) error {
+	dat, err := xml.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err
+	}
+
+	fp, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	if _, err := fp.WriteString(xml.Header); err != nil {
+		return err
+	}
+	_, err = fp.Write(append(dat, '\n'))
+	return err
+}
+
+// fetchImage fetches and decodes the image at the specified uri.
+func fetchImage(uri string) (image.Image, error) {
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(dat))
+	if err != nil {
+		return nil, fmt.Errorf("image <%s>: %s", uri, err)
+	}
+
+	return img, nil
+}
+
+// tmxColor converts a Tiled color (#AARRGGBB or #RRGGBB) to #RRGGBBAA.
+func tmxColor(src string) string {
+	src = strings.TrimPrefix(src, "#")
+	switch len(src) {
+	case 6:
+		return "#" + src
+	case 8:
+		return "#" + src[2:] + src[:2]
+	default:
+		return "#00000000"
+	}
+}
+
+// mat2 is a 2x2 matrix describing a tile orientation
+// (y axis pointing down).
+type mat2 [4]int
+
+func (m mat2) mul(n mat2) mat2 {
+	return mat2{
+		m[0]*n[0] + m[1]*n[2], m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2], m[2]*n[1] + m[3]*n[3],
+	}
+}
+
+var (
+	matI = mat2{1, 0, 0, 1}
+	matH = mat2{-1, 0, 0, 1}
+	matV = mat2{1, 0, 0, -1}
+	matD = mat2{0, 1, 1, 0}
+	matR = mat2{0, -1, 1, 0} // 90° clockwise
+)
+
+// matrix returns the orientation of the cell:
+// flips first, then the clockwise rotation.
+func (c cell) matrix() mat2 {
+	m := matI
+	if c.flipH {
+		m = matH.mul(m)
+	}
+	if c.flipV {
+		m = matV.mul(m)
+	}
+	for i := 0; i < c.rotation/90; i++ {
+		m = matR.mul(m)
+	}
+	return m
+}
+
+// gidFlags returns the Tiled flip flags matching the cell orientation;
+// Tiled applies the diagonal flip first, then horizontal and vertical.
+func (c cell) gidFlags() uint32 {
+	want := c.matrix()
+	for flags := uint32(0); flags < 8; flags++ {
+		gid := flags << 29
+		if tmxMatrix(gid) == want {
+			return gid
+		}
+	}
+	return 0
+}
+
+// tmxMatrix returns the orientation encoded in the global tile id flags.
+func tmxMatrix(gid uint32) mat2 {
+	m := matI
+	if gid&tmxFlipD != 0 {
+		m = matD.mul(m)
+	}
+	if gid&tmxFlipH != 0 {
+		m = matH.mul(m)
+	}
+	if gid&tmxFlipV != 0 {
+		m = matV.mul(m)
+	}
+	return m
+}
+
+// cellFromGID converts a Tiled global tile id to a layout cell.
+func cellFromGID(gid uint32) cell {
+	want := tmxMatrix(gid)
+	for rot := 0; rot < 360; rot += 90 {
+		for _, fv := range []bool{false, true} {
+			res := cell{index: int(gid &^ tmxFlags), rotation: rot, flipV: fv}
+			if res.matrix() == want {
+				return res
+			}
+		}
+	}
+	return cell{index: int(gid &^ tmxFlags)}
+}
+
+func maxInt(a, b int) int {
+	if b > a {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,202 @@
+package tilemap
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tileset"
+)
+
+func TestGIDFlags(t *testing.T) {
+	tests := []struct {
+		flags uint32
+		want  cell
+	}{
+		{0, cell{index: 7}},
+		{tmxFlipD | tmxFlipH, cell{index: 7, rotation: 90}},
+		{tmxFlipH | tmxFlipV, cell{index: 7, rotation: 180}},
+		{tmxFlipD | tmxFlipV, cell{index: 7, rotation: 270}},
+		{tmxFlipV, cell{index: 7, flipV: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			got := cellFromGID(7 | tt.flags)
+			if got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+			if flags := got.gidFlags(); flags != tt.flags {
+				t.Errorf("got [%x] want [%x]", flags, tt.flags)
+			}
+		})
+	}
+
+	// every orientation must survive the round trip
+	for flags := uint32(0); flags < 8; flags++ {
+		gid := flags << 29
+		if got := cellFromGID(gid).gidFlags(); got != gid {
+			t.Errorf("got [%x] want [%x]", got, gid)
+		}
+	}
+}
+
+func TestDecodeTMXData(t *testing.T) {
+	want := []uint32{1, 0, 2, 3 | tmxFlipH}
+
+	raw := make([]byte, 4*len(want))
+	for i, el := range want {
+		binary.LittleEndian.PutUint32(raw[i*4:], el)
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(raw)
+	zw.Close()
+
+	tests := []tmxData{
+		{Encoding: "csv", Content: "\n1,0,2,\n2147483651\n"},
+		{Encoding: "base64", Content: base64.StdEncoding.EncodeToString(raw)},
+		{Encoding: "base64", Compression: "zlib", Content: base64.StdEncoding.EncodeToString(buf.Bytes())},
+		{Tiles: []tmxDataTile{{1}, {0}, {2}, {3 | tmxFlipH}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Encoding+tt.Compression, func(t *testing.T) {
+			got, err := decodeTMXData(tt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("got [%v] want [%v]", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("got [%v] want [%v]", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTMXTilesetUnnamed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fp, err := os.Create(filepath.Join(dir, "sheet.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = png.Encode(fp, image.NewNRGBA(image.Rect(0, 0, 16, 8)))
+	fp.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping := map[int]tileRef{}
+	for _, gid := range []uint32{1, 3} {
+		src := tmxTileset{
+			FirstGID: gid, TileWidth: 8, TileHeight: 8,
+			Image: &tmxImage{Source: "sheet.png"},
+		}
+		if _, err := decodeTMXTileset(filepath.Join(dir, "map.tmx"), src, mapping); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for gid, el := range mapping {
+		if seen[el.id] {
+			t.Errorf("gid %d: duplicate tile id [%v]", gid, el.id)
+		}
+		seen[el.id] = true
+	}
+	if got, want := len(seen), 4; got != want {
+		t.Errorf("got [%v] want [%v]", got, want)
+	}
+}
+
+func TestExportTMX(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tm, err := Load("../examples/tilemap_demo_1.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tm.ExportTMX(dir, "demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Load(filepath.Join(dir, "demo.tmx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.cols != tm.cols || res.rows != tm.rows {
+		t.Fatalf("got [%dx%d] want [%dx%d]", res.cols, res.rows, tm.cols, tm.rows)
+	}
+
+	for i := 0; i < tm.cols*tm.rows; i++ {
+		want := tm.mapping[tm.layers[0].layout[i].index].id
+		got := res.mapping[res.layers[0].layout[i].index].id
+		if got != want {
+			t.Errorf("cell %d: got [%v] want [%v]", i, got, want)
+		}
+	}
+}
+
+func TestExportTMXHex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	layout, err := parseLayout("1,0,0,1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tm := TileMap{
+		cols: 2, rows: 2, tileSize: 8,
+		gridType: grid.TypeHex, hexOrientation: grid.HexFlat, hexOffset: grid.OffsetEven,
+		mapping: map[int]tileRef{1: {id: "a"}},
+		layers:  []*layer{{name: defaultLayerName, layout: layout, opacity: 1, visible: true}},
+		repo:    []*tileset.Tileset{testTileset(t)},
+	}
+
+	if err := tm.ExportTMX(dir, "hex"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Load(filepath.Join(dir, "hex.tmx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{res.gridType, res.hexOrientation, res.hexOffset, strconv.Itoa(res.tileSize)}
+	want := []string{grid.TypeHex, grid.HexFlat, grid.OffsetEven, "8"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got [%v] want [%v]", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package tilemap
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lucasepe/tiles/data"
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tileset"
+	"gopkg.in/yaml.v2"
+)
+
+// Problem is an issue found validating a tilemap;
+// Line is the source line (zero if unknown).
+type Problem struct {
+	Line    int
+	Message string
+}
+
+// String returns the problem as 'line N: message'.
+func (p Problem) String() string {
+	if p.Line <= 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+var (
+	yamlLineRE = regexp.MustCompile(`^yaml: line (\d+): `)
+	typeLineRE = regexp.MustCompile(`^line (\d+): `)
+)
+
+// decodeIssue is a problem found decoding the tilemap; locate
+// returns its line using the source outline (nil if unknown).
+type decodeIssue struct {
+	err    error
+	locate func(src *outlineNode) int
+}
+
+// keyLine returns a function that locates the key at the specified path.
+func keyLine(path ...string) func(src *outlineNode) int {
+	return func(src *outlineNode) int {
+		return src.find(path...).lineOf()
+	}
+}
+
+// Validate checks the tilemap at the specified uri and returns
+// all the problems found; the error is returned only if the
+// tilemap can not be fetched.
+func Validate(uri string) ([]Problem, error) {
+	if strings.EqualFold(filepath.Ext(uri), ".tmx") {
+		tm, err := LoadTMX(uri)
+		if err != nil {
+			return []Problem{{Message: err.Error()}}, nil
+		}
+		return tm.validate(nil), nil
+	}
+
+	dat, err := data.Fetch(uri, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	// lenient decoding collects the problems and goes on
+	tm := TileMap{lenient: true}
+	if err := yaml.Unmarshal(dat, &tm); err != nil {
+		msg := err.Error()
+		if m := yamlLineRE.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return []Problem{{Line: line, Message: msg[len(m[0]):]}}, nil
+		}
+		return []Problem{{Message: msg}}, nil
+	}
+
+	return tm.validate(outline(dat)), nil
+}
+
+// validate checks the decoded tilemap; the source
+// outline (optional) is used to locate the problems.
+func (tm *TileMap) validate(src *outlineNode) []Problem {
+	res := []Problem{}
+	report := func(line int, format string, args ...interface{}) {
+		res = append(res, Problem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, el := range tm.issues {
+		msg, line := el.err.Error(), 0
+		if el.locate != nil {
+			line = el.locate(src)
+		} else if m := typeLineRE.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = msg[len(m[0]):]
+		}
+		report(line, "%s", msg)
+	}
+
+	if tm.cols <= 0 {
+		report(src.find("cols").lineOf(), "cols must be greater than zero")
+	}
+	if tm.rows <= 0 {
+		report(src.find("rows").lineOf(), "rows must be greater than zero")
+	}
+	if w, h := tm.tileRect(); w <= 0 || h <= 0 {
+		node := src.find("tile_size")
+		if node == nil {
+			node = src.find("tile_width")
+		}
+		report(node.lineOf(), "tile_size must be greater than zero")
+	}
+
+	if tm.bgColor != "" && !grid.IsHexColor(tm.bgColor) {
+		report(src.find("bg_color").lineOf(), "invalid bg_color %q", tm.bgColor)
+	}
+
+	colors := []struct{ section, key, val string }{
+		{"grid", "line_color", tm.grid.lineColor},
+		{"grid", "border_color", tm.grid.borderColor},
+		{"watermark", "color", tm.watermark.color},
+	}
+	for _, el := range colors {
+		if el.val != "" && !grid.IsHexColor(el.val) {
+			report(src.find(el.section, el.key).lineOf(), "invalid %s %s %q", el.section, el.key, el.val)
+		}
+	}
+
+	for _, lb := range tm.labels {
+		if lb.color != "" && !grid.IsHexColor(lb.color) {
+			report(src.find("labels", lb.ref).lineOf(), "label %q: invalid color %q", lb.ref, lb.color)
+		}
+	}
+
+	if t := tm.gridType; len(tm.connections) > 0 && t != "" && t != grid.TypeSquare {
+		report(src.find("connections").lineOf(), "connections are supported on square grids only, not %s", t)
+	}
+
+	for i, cn := range tm.connections {
+		line := src.find("connections").item(i).lineOf()
+		if cn.color != "" && !grid.IsHexColor(cn.color) {
+			report(line, "connection %d: invalid color %q", i, cn.color)
+		}
+		for _, ep := range []endpoint{cn.from, cn.to} {
+			if ep.row < 0 || ep.row >= tm.rows || ep.col < 0 || ep.col >= tm.cols {
+				report(line, "connection %d: cell (%d, %d) is out of bounds", i, ep.row, ep.col)
+			}
+		}
+		if cn.from.row == cn.to.row && cn.from.col == cn.to.col {
+			report(line, "connection %d: from and to are the same cell (%d, %d)", i, cn.from.row, cn.from.col)
+		}
+	}
+
+	var checkGroups func(src []*group, node *outlineNode)
+	checkGroups = func(src []*group, node *outlineNode) {
+		for i, gp := range src {
+			item := node.item(i)
+			for _, el := range []struct{ key, val string }{
+				{"border_color", gp.borderColor},
+				{"fill_color", gp.fillColor},
+			} {
+				if el.val != "" && !grid.IsHexColor(el.val) {
+					report(item.find(el.key).lineOf(), "group %q: invalid %s %q", gp.title, el.key, el.val)
+				}
+			}
+
+			r0, c0, r1, c1 := gp.bounds()
+			if r0 < 0 || c0 < 0 || r1 >= tm.rows || c1 >= tm.cols {
+				report(item.lineOf(), "group %q: cells (%d, %d) to (%d, %d) are out of bounds", gp.title, r0, c0, r1, c1)
+			}
+
+			checkGroups(gp.groups, item.find("groups"))
+		}
+	}
+	checkGroups(tm.groups, src.find("groups"))
+
+	repo := tm.repo
+	if repo == nil {
+		node := src.find("atlas_list")
+		for i, uri := range tm.atlasList {
+			ts, err := tileset.Load(uri)
+			if err != nil {
+				report(node.item(i).lineOf(), "atlas %q: %s", uri, err)
+				continue
+			}
+			repo = append(repo, ts...)
+		}
+	}
+
+	if id := tm.watermark.tile; id != "" {
+		if _, ok := lookupTile(repo, id); !ok {
+			report(src.find("watermark", "tile").lineOf(), "watermark tile %q not found in atlas list", id)
+		}
+	}
+
+	rules := tm.autotiles(repo)
+
+	used := make(map[int]bool)
+	broken := false
+	for i, ly := range tm.layers {
+		node := tm.layoutNode(src, i)
+
+		// the layout could not be decoded (already reported)
+		if ly.layout == nil && !ly.spec.empty() {
+			broken = true
+			continue
+		}
+
+		if want := tm.rows * tm.cols; len(ly.layout) != want {
+			report(node.lineOf(), "layer %q: layout has %d cells, expected %d (%d rows x %d cols)",
+				ly.name, len(ly.layout), want, tm.rows, tm.cols)
+		}
+
+		for pos, el := range ly.layout {
+			if el.empty() {
+				continue
+			}
+
+			row, col := 0, pos
+			if tm.cols > 0 {
+				row, col = pos/tm.cols, pos%tm.cols
+			}
+
+			if el.name == "" {
+				used[el.index] = true
+				if _, ok := tm.mapping[el.index]; !ok {
+					report(ly.cellLine(node, pos, tm.cols), "layer %q: index %d at cell (%d, %d) is not defined in mapping",
+						ly.name, el.index, row, col)
+				}
+				continue
+			}
+
+			if _, ok := tm.aliases[el.name]; ok {
+				continue
+			}
+
+			if _, ok := lookupTile(repo, el.name); !ok {
+				report(ly.cellLine(node, pos, tm.cols), "layer %q: tile %q at cell (%d, %d) not found in atlas list",
+					ly.name, el.name, row, col)
+			}
+		}
+	}
+
+	keys := make([]int, 0, len(tm.mapping))
+	for k := range tm.mapping {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	for _, k := range keys {
+		line := src.find("mapping", strconv.Itoa(k)).lineOf()
+		if !used[k] && !broken {
+			report(line, "mapping index %d is never used in the layout", k)
+		}
+
+		for _, msg := range tm.checkRef(repo, rules, tm.mapping[k]) {
+			report(line, "mapping index %d: %s", k, msg)
+		}
+	}
+
+	names := make([]string, 0, len(tm.aliases))
+	for k := range tm.aliases {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		node := src.find("aliases", k)
+		if node == nil {
+			node = src.find("legend", k)
+		}
+
+		for _, msg := range tm.checkRef(repo, rules, tm.aliases[k]) {
+			report(node.lineOf(), "alias %q: %s", k, msg)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Line < res[j].Line
+	})
+
+	return res
+}
+
+// layoutNode returns the outline node of the
+// layout of the i-th layer (nil if unknown).
+func (tm *TileMap) layoutNode(src *outlineNode, i int) *outlineNode {
+	if top := src.find("layout"); top != nil {
+		if i == 0 {
+			return top
+		}
+		i--
+	}
+
+	return src.find("layers").item(i).find("layout")
+}
+
+// cellLine returns the line of the layout
+// cell at pos (node is the layout node).
+func (ly *layer) cellLine(node *outlineNode, pos, cols int) int {
+	if ly.spec.art != "" && !ly.spec.ascii {
+		return node.cellLine(pos)
+	}
+	if cols <= 0 {
+		return node.lineOf()
+	}
+	return node.rowLine(pos / cols)
+}
+
+// errorLine returns the line of the layout
+// error (node is the layout node).
+func (ly *layer) errorLine(node *outlineNode, err error, cols int) int {
+	le, ok := err.(*layoutError)
+	switch {
+	case !ok:
+		return node.lineOf()
+	case le.row >= 0:
+		return node.rowLine(le.row)
+	case le.pos >= 0:
+		return ly.cellLine(node, le.pos, cols)
+	}
+	return node.lineOf()
+}
+
+// checkRef verifies that the tile (or the autotile tiles)
+// referenced by a mapping entry exists and fits its cells.
+func (tm *TileMap) checkRef(repo []*tileset.Tileset, rules map[string]*tileset.Autotile, ref tileRef) []string {
+	if ref.autotile == "" {
+		return tm.checkTile(repo, ref, ref.id)
+	}
+
+	at, ok := rules[ref.autotile]
+	if !ok {
+		return []string{fmt.Sprintf("autotile %q not found", ref.autotile)}
+	}
+
+	specs := []string{}
+	if at.Default != "" {
+		specs = append(specs, at.Default)
+	}
+	for _, v := range at.Rules {
+		specs = append(specs, v)
+	}
+	sort.Strings(specs)
+
+	res := []string{}
+	seen := make(map[string]bool)
+	for _, el := range specs {
+		id := strings.Split(el, ":")[0]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		res = append(res, tm.checkTile(repo, ref, id)...)
+	}
+
+	return res
+}
+
+// checkTile verifies that the tile exists and that its
+// size is not larger than the cells it covers.
+func (tm *TileMap) checkTile(repo []*tileset.Tileset, ref tileRef, id string) []string {
+	tile, ok := lookupTile(repo, id)
+	if !ok {
+		return []string{fmt.Sprintf("tile %q not found in atlas list", id)}
+	}
+
+	tw, th := tm.tileRect()
+	if tw <= 0 || th <= 0 {
+		return nil
+	}
+
+	// the fit modes scale the images on purpose
+	mode := tm.fit
+	if ref.fit != "" {
+		mode = ref.fit
+	}
+	if mode != "" && mode != grid.FitNone {
+		return nil
+	}
+
+	cols, rows := spanOf(ref, tile)
+	w, h := tile.MaxX-tile.MinX, tile.MaxY-tile.MinY
+	if w > cols*tw || h > rows*th {
+		return []string{fmt.Sprintf("tile %q is %dx%d, larger than the %dx%d area it covers",
+			id, w, h, cols*tw, rows*th)}
+	}
+
+	return nil
+}
+
+// lookupTile returns the tile with the specified id.
+func lookupTile(repo []*tileset.Tileset, id string) (tileset.Tile, bool) {
+	for _, el := range repo {
+		if tile, ok := el.Get(id); ok {
+			return tile, true
+		}
+	}
+
+	return tileset.Tile{}, false
+}
@@ -0,0 +1,297 @@
+package tilemap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestOutline(t *testing.T) {
+	src := `cols: 2
+# comment
+mapping:
+  1: aws_lambda
+  "2": aws_api_gateway
+layout: >
+  1,2
+  0,1
+layers:
+  - name: roads
+    layout: 0,0,1,1
+`
+	root := outline([]byte(src))
+
+	tests := []struct {
+		node *outlineNode
+		want int
+	}{
+		{root.find("cols"), 1},
+		{root.find("mapping", "1"), 4},
+		{root.find("mapping", "2"), 5},
+		{root.find("layers").item(0).find("name"), 10},
+		{root.find("layers").item(0).find("layout"), 11},
+		{root.find("layers").item(1), 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.node.lineOf(); got != tt.want {
+			t.Errorf("got [%v] want [%v]", got, tt.want)
+		}
+	}
+
+	if got := root.find("layout").rowLine(1); got != 8 {
+		t.Errorf("got [%v] want [%v]", got, 8)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	atlas, err := filepath.Abs("../examples/links_tileset.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := `cols: 2
+rows: 2
+tile_size: 128
+bg_color: "#zzz"
+atlas_list:
+  - ` + atlas + `
+mapping:
+  1: link_vertical
+  2: link_horizontal
+  3: link_missing
+layout: >
+  1,2
+  7,1
+`
+	uri := filepath.Join(dir, "map.yml")
+	if err := ioutil.WriteFile(uri, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Validate(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{Line: 4, Message: `invalid bg_color "#zzz"`},
+		{Line: 10, Message: `mapping index 3 is never used in the layout`},
+		{Line: 10, Message: `mapping index 3: tile "link_missing" not found in atlas list`},
+		{Line: 13, Message: `layer "default": index 7 at cell (1, 0) is not defined in mapping`},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got [%v] want [%v]", got[i], want[i])
+		}
+	}
+}
+
+func TestValidateDecodeErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `cols: 2
+rows: x
+tile_size: 64
+bg_color: "#zzz"
+grid_type: triangle
+mapping:
+  1: aws_lambda
+layout: >
+  1,1
+  x,1
+layers:
+  - name: top
+    layout:
+      - [aws_lambda, "1:r45"]
+      - [., .]
+`
+	uri := filepath.Join(dir, "map.yml")
+	if err := ioutil.WriteFile(uri, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Validate(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{Line: 2, Message: "cannot unmarshal !!str `x` into int"},
+		{Line: 2, Message: "rows must be greater than zero"},
+		{Line: 4, Message: `invalid bg_color "#zzz"`},
+		{Line: 5, Message: `unsupported grid_type "triangle"`},
+		{Line: 7, Message: `mapping index 1: tile "aws_lambda" not found in atlas list`},
+		{Line: 10, Message: `layer "default": strconv.Atoi: parsing "x": invalid syntax`},
+		{Line: 14, Message: `layer "top": invalid transform "r45" in cell "1:r45"`},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got [%v] want [%v]", got[i], want[i])
+		}
+	}
+}
+
+func TestValidateFoldedLayout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	atlas, err := filepath.Abs("../examples/links_tileset.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := `cols: 3
+rows: 2
+tile_size: 128
+atlas_list:
+  - ` + atlas + `
+mapping:
+  1: link_vertical
+layout: >
+  1,1,1,1
+  1
+  7
+layers:
+  - name: top
+    layout: 1 1 1
+      1 x 1
+`
+	uri := filepath.Join(dir, "map.yml")
+	if err := ioutil.WriteFile(uri, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Validate(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{Line: 11, Message: `layer "default": index 7 at cell (1, 2) is not defined in mapping`},
+		{Line: 15, Message: `layer "top": strconv.Atoi: parsing "x": invalid syntax`},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got [%v] want [%v]", got[i], want[i])
+		}
+	}
+}
+
+func TestValidateLabelsAndConnections(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `cols: 2
+rows: 1
+tile_size: 64
+labels:
+  0,0: { text: api, anchor: left }
+  0,1: { text: db, anchor: Inside }
+connections:
+  - { from: [0, 0], to: [0, 1] }
+  - { from: [0, 1], to: "0,1" }
+`
+	uri := filepath.Join(dir, "map.yml")
+	if err := ioutil.WriteFile(uri, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Validate(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{Message: `invalid label anchor "left" (below, above or inside)`},
+		{Line: 9, Message: `connection 1: from and to are the same cell (0, 1)`},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got [%v] want [%v]", got[i], want[i])
+		}
+	}
+
+	var tm TileMap
+	if err := yaml.Unmarshal([]byte(src), &tm); err == nil {
+		t.Errorf("got [%v] want [invalid label anchor]", err)
+	}
+}
+
+func TestValidateConnectionsGridType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := `cols: 2
+rows: 1
+tile_size: 64
+grid_type: hex
+connections:
+  - { from: [0, 0], to: [0, 1] }
+`
+	uri := filepath.Join(dir, "map.yml")
+	if err := ioutil.WriteFile(uri, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Validate(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Problem{
+		{Line: 5, Message: `connections are supported on square grids only, not hex`},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got [%v] want [%v]", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got [%v] want [%v]", got[i], want[i])
+		}
+	}
+}
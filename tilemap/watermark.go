@@ -0,0 +1,107 @@
+package tilemap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucasepe/tiles/grid"
+	"github.com/lucasepe/tiles/tileset"
+)
+
+// watermark is the text (or the tile image)
+// drawn over the tilemap and its style.
+type watermark struct {
+	text     string
+	position string
+	color    string
+	angle    float64
+	size     float64
+	font     string
+	tiled    bool
+	tile     string
+	opacity  float64
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg.
+// A watermark can be a plain text or an object like:
+//
+//	{ text: DRAFT, position: bottom-right, color: "#ff000066", angle: -45, tiled: true }
+//	{ tile: company_logo, opacity: 0.3 }
+func (wm *watermark) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err == nil {
+		wm.text, wm.opacity = text, 1
+		return nil
+	}
+
+	aux := struct {
+		Text     string   `yaml:"text"`
+		Position string   `yaml:"position"`
+		Color    string   `yaml:"color"`
+		Angle    float64  `yaml:"angle"`
+		Size     float64  `yaml:"size"`
+		Font     string   `yaml:"font"`
+		Tiled    bool     `yaml:"tiled"`
+		Tile     string   `yaml:"tile"`
+		Opacity  *float64 `yaml:"opacity"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	wm.text = aux.Text
+	wm.position = strings.ToLower(aux.Position)
+	wm.color = aux.Color
+	wm.angle = aux.Angle
+	wm.size = aux.Size
+	wm.font = aux.Font
+	wm.tiled = aux.Tiled
+	wm.tile = aux.Tile
+
+	switch wm.position {
+	case "", grid.PositionCenter, grid.PositionTopLeft, grid.PositionTopRight,
+		grid.PositionBottomLeft, grid.PositionBottomRight:
+	default:
+		return fmt.Errorf("invalid watermark position %q", aux.Position)
+	}
+
+	wm.opacity = 1
+	if aux.Opacity != nil {
+		wm.opacity = *aux.Opacity
+	}
+	if wm.opacity < 0 || wm.opacity > 1 {
+		return fmt.Errorf("watermark opacity must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// drawOptions returns the grid options that apply the watermark
+// style; the font and the tile image are fetched here.
+func (wm *watermark) drawOptions(repo []*tileset.Tileset) ([]func(*grid.WatermarkOptions), error) {
+	font, err := loadFont(wm.font)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []func(*grid.WatermarkOptions){
+		grid.WatermarkPosition(wm.position),
+		grid.WatermarkColor(wm.color),
+		grid.WatermarkAngle(wm.angle),
+		grid.WatermarkSize(wm.size),
+		grid.WatermarkFont(font),
+		grid.WatermarkTiled(wm.tiled),
+		grid.WatermarkOpacity(wm.opacity),
+	}
+
+	if wm.tile != "" {
+		img, _, err := findTileByID(repo, wm.tile)
+		if err != nil {
+			return nil, fmt.Errorf("watermark: %s", err)
+		}
+		res = append(res, grid.WatermarkImage(img, wm.opacity))
+	}
+
+	return res, nil
+}
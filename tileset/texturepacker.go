@@ -0,0 +1,180 @@
+package tileset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tpRect is a TexturePacker rectangle.
+type tpRect struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// tpSize is a TexturePacker size.
+type tpSize struct {
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// tpFrame is a TexturePacker frame (Filename is set
+// only in the JSON Array format).
+type tpFrame struct {
+	Filename         string `json:"filename,omitempty"`
+	Frame            tpRect `json:"frame"`
+	Rotated          bool   `json:"rotated"`
+	Trimmed          bool   `json:"trimmed"`
+	SpriteSourceSize tpRect `json:"spriteSourceSize"`
+	SourceSize       tpSize `json:"sourceSize"`
+}
+
+// trimmedOff returns true if the frame is smaller than the
+// source image (or moved within it) because of the trimming.
+func (fr tpFrame) trimmedOff() bool {
+	ss := fr.SpriteSourceSize
+	return ss.X != 0 || ss.Y != 0 ||
+		fr.Frame.W != fr.SourceSize.W || fr.Frame.H != fr.SourceSize.H
+}
+
+// tpMeta is the TexturePacker metadata.
+type tpMeta struct {
+	App     string `json:"app"`
+	Version string `json:"version"`
+	Image   string `json:"image"`
+	Format  string `json:"format"`
+	Size    tpSize `json:"size"`
+	Scale   string `json:"scale"`
+}
+
+// MarshalTexturePacker returns the tile set as a TexturePacker
+// JSON atlas (as used by Phaser and PixiJS): JSON Hash, or JSON
+// Array if array is true. The atlas image is referenced by
+// ImageFile, so it must be set.
+func (ts *Tileset) MarshalTexturePacker(array bool) ([]byte, error) {
+	if ts.ImageFile == "" {
+		return nil, fmt.Errorf("a JSON atlas needs an external image file")
+	}
+
+	frames := make([]tpFrame, len(ts.Tiles))
+	for i, el := range ts.Tiles {
+		w, h := el.MaxX-el.MinX, el.MaxY-el.MinY
+		frames[i] = tpFrame{
+			Filename:         el.ID,
+			Frame:            tpRect{X: el.MinX, Y: el.MinY, W: w, H: h},
+			SpriteSourceSize: tpRect{W: w, H: h},
+			SourceSize:       tpSize{W: w, H: h},
+		}
+	}
+
+	meta := tpMeta{
+		App:     "https://github.com/lucasepe/tiles",
+		Version: "1.0",
+		Image:   ts.ImageFile,
+		Format:  "RGBA8888",
+		Size:    tpSize{W: ts.Width, H: ts.Height},
+		Scale:   "1",
+	}
+
+	if array {
+		return json.MarshalIndent(struct {
+			Frames []tpFrame `json:"frames"`
+			Meta   tpMeta    `json:"meta"`
+		}{frames, meta}, "", "   ")
+	}
+
+	hash := make(map[string]tpFrame, len(frames))
+	for _, el := range frames {
+		id := el.Filename
+		el.Filename = ""
+		hash[id] = el
+	}
+
+	return json.MarshalIndent(struct {
+		Frames map[string]tpFrame `json:"frames"`
+		Meta   tpMeta             `json:"meta"`
+	}{hash, meta}, "", "   ")
+}
+
+// isJSON returns true if the data looks like a JSON object.
+func isJSON(dat []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(dat), []byte("{"))
+}
+
+// unmarshalTexturePacker decodes a TexturePacker JSON atlas (Hash
+// or Array format); the tile ids are the frame names without the
+// image extension. Rotated and trimmed frames are not supported.
+func unmarshalTexturePacker(dat []byte, res *Tileset) error {
+	aux := struct {
+		Frames json.RawMessage `json:"frames"`
+		Meta   tpMeta          `json:"meta"`
+	}{}
+
+	if err := json.Unmarshal(dat, &aux); err != nil {
+		return err
+	}
+
+	var frames []tpFrame
+	if bytes.HasPrefix(bytes.TrimSpace(aux.Frames), []byte("[")) {
+		if err := json.Unmarshal(aux.Frames, &frames); err != nil {
+			return err
+		}
+	} else {
+		hash := map[string]tpFrame{}
+		if err := json.Unmarshal(aux.Frames, &hash); err != nil {
+			return err
+		}
+
+		names := make([]string, 0, len(hash))
+		for k := range hash {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			el := hash[k]
+			el.Filename = k
+			frames = append(frames, el)
+		}
+	}
+
+	if aux.Meta.Image == "" {
+		return fmt.Errorf("missing meta.image in JSON atlas")
+	}
+
+	res.ImageFile = aux.Meta.Image
+	res.Width = aux.Meta.Size.W
+	res.Height = aux.Meta.Size.H
+	res.Tiles = make([]*Tile, len(frames))
+
+	for i, el := range frames {
+		if el.Rotated {
+			return fmt.Errorf("frame %q: rotated frames are not supported", el.Filename)
+		}
+
+		// tiles have no offset: the transparent border removed
+		// by the trimming would be lost (as well as the size)
+		if el.Trimmed && el.trimmedOff() {
+			return fmt.Errorf("frame %q: trimmed frames are not supported", el.Filename)
+		}
+
+		id := el.Filename
+		switch strings.ToLower(filepath.Ext(id)) {
+		case ".png", ".jpg", ".jpeg", ".gif", ".webp":
+			id = strings.TrimSuffix(id, filepath.Ext(id))
+		}
+
+		res.Tiles[i] = &Tile{
+			ID:   id,
+			MinX: el.Frame.X, MinY: el.Frame.Y,
+			MaxX: el.Frame.X + el.Frame.W, MaxY: el.Frame.Y + el.Frame.H,
+		}
+	}
+
+	return nil
+}
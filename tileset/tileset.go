@@ -5,7 +5,8 @@ import (
 	"encoding/base64"
 	"fmt"
 	"image"
-	_ "image/png" // load the PNG driver
+	"image/draw"
+	"image/png"
 	"io"
 	"strings"
 	"time"
@@ -16,12 +17,16 @@ import (
 )
 
 // Tile describes a tile in the tiles set.
+// SpanCols and SpanRows (optional) specify how many
+// grid cells the tile occupies when rendered in a tilemap.
 type Tile struct {
-	ID   string `yaml:"id"`
-	MinX int    `yaml:"minX"`
-	MinY int    `yaml:"minY"`
-	MaxX int    `yaml:"maxX"`
-	MaxY int    `yaml:"maxY"`
+	ID       string `yaml:"id"`
+	MinX     int    `yaml:"minX"`
+	MinY     int    `yaml:"minY"`
+	MaxX     int    `yaml:"maxX"`
+	MaxY     int    `yaml:"maxY"`
+	SpanCols int    `yaml:"spanCols,omitempty"`
+	SpanRows int    `yaml:"spanRows,omitempty"`
 }
 
 // Rect returns the image rectangle fot the tile.
@@ -29,12 +34,57 @@ func (t *Tile) Rect() image.Rectangle {
 	return image.Rect(t.MinX, t.MinY, t.MaxX, t.MaxY)
 }
 
-// Tileset describes a tile set.
+// Autotile describes the rules used to pick a tile according
+// to the neighbouring cells of the same kind. Each rule maps a
+// bitmask to a tile id (with optional transforms, i.e. 'link:r90').
+//
+// With 4 neighbours (the default) the bits are N=1, E=2, S=4, W=8;
+// with 8 neighbours N=1, NE=2, E=4, SE=8, S=16, SW=32, W=64, NW=128
+// and corners are set only if both adjacent sides are set.
+type Autotile struct {
+	Neighbours int            `yaml:"neighbours"`
+	Rules      map[int]string `yaml:"rules"`
+	Default    string         `yaml:"default,omitempty"`
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of the yaml pkg;
+// neighbours must be 4 (the default) or 8.
+func (at *Autotile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Autotile
+	aux := plain{}
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+
+	switch aux.Neighbours {
+	case 0:
+		aux.Neighbours = 4
+	case 4, 8:
+	default:
+		return fmt.Errorf("invalid autotile neighbours %d, must be 4 or 8", aux.Neighbours)
+	}
+
+	max := 1 << uint(aux.Neighbours)
+	for mask := range aux.Rules {
+		if mask < 0 || mask >= max {
+			return fmt.Errorf("invalid autotile mask %d with %d neighbours", mask, aux.Neighbours)
+		}
+	}
+
+	*at = Autotile(aux)
+	return nil
+}
+
+// Tileset describes a tile set. The atlas image is either
+// an external PNG (ImageFile, relative to the tile set location)
+// or embedded as base64 (Data).
 type Tileset struct {
-	Tiles  []*Tile `yaml:"tiles,omitempty"`
-	Width  int     `yaml:"width"`
-	Height int     `yaml:"height"`
-	Data   string  `yaml:"data"`
+	Tiles     []*Tile              `yaml:"tiles,omitempty"`
+	Autotiles map[string]*Autotile `yaml:"autotiles,omitempty"`
+	Width     int                  `yaml:"width"`
+	Height    int                  `yaml:"height"`
+	ImageFile string               `yaml:"image,omitempty"`
+	Data      string               `yaml:"data,omitempty"`
 
 	uri string
 }
@@ -54,6 +104,35 @@ func Load(uri ...string) ([]*Tileset, error) {
 	return res, nil
 }
 
+// FromImage creates a tile set from an in-memory image;
+// the uri is used as the image cache key.
+func FromImage(uri string, img image.Image, tiles []*Tile) (*Tileset, error) {
+	if _, ok := img.(subImager); !ok || img.Bounds().Min != (image.Point{}) {
+		b := img.Bounds()
+		dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+		img = dst
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	res := &Tileset{
+		Tiles:  tiles,
+		Width:  b.Dx(),
+		Height: b.Dy(),
+		Data:   base64.StdEncoding.EncodeToString(buf.Bytes()),
+		uri:    uri,
+	}
+
+	storage.Set(uri, img, cache.DefaultExpiration)
+
+	return res, nil
+}
+
 // loadOne fetches a single tile set from the specified uri.
 func loadOne(uri string) (*Tileset, error) {
 	dat, err := data.Fetch(uri, -1)
@@ -62,6 +141,15 @@ func loadOne(uri string) (*Tileset, error) {
 	}
 
 	res := &Tileset{uri: uri}
+
+	// TexturePacker JSON atlases (Phaser, PixiJS)
+	if isJSON(dat) {
+		if err := unmarshalTexturePacker(dat, res); err != nil {
+			return nil, fmt.Errorf("%s: %s", uri, err)
+		}
+		return res, nil
+	}
+
 	if err := yaml.Unmarshal(dat, &res); err != nil {
 		return nil, err
 	}
@@ -78,6 +166,7 @@ func (ts *Tileset) Get(id string) (Tile, bool) {
 				ID:   el.ID,
 				MinX: el.MinX, MinY: el.MinY,
 				MaxX: el.MaxX, MaxY: el.MaxY,
+				SpanCols: el.SpanCols, SpanRows: el.SpanRows,
 			}, true
 		}
 	}
@@ -96,6 +185,16 @@ func (ts *Tileset) List(wr io.Writer) error {
 	return nil
 }
 
+// URI returns the location the tile set was loaded from.
+func (ts *Tileset) URI() string {
+	return ts.uri
+}
+
+// Atlas returns the whole tile set image.
+func (ts *Tileset) Atlas() (image.Image, error) {
+	return ts.cachedImage()
+}
+
 // Image returns the tile image.
 func (ts *Tileset) Image(tile Tile) (image.Image, error) {
 	img, err := ts.cachedImage()
@@ -113,12 +212,7 @@ func (ts *Tileset) cachedImage() (image.Image, error) {
 		return res, nil
 	}
 
-	data, err := base64.StdEncoding.DecodeString(ts.Data)
-	if err != nil {
-		return nil, err
-	}
-
-	img, _, err := image.Decode(bytes.NewReader(data))
+	img, err := ts.decodeImage()
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +222,37 @@ func (ts *Tileset) cachedImage() (image.Image, error) {
 	return img, nil
 }
 
+// decodeImage reads the external atlas image, if any,
+// falling back to the embedded data when present.
+func (ts *Tileset) decodeImage() (image.Image, error) {
+	var dat []byte
+	var err error
+
+	if ts.ImageFile != "" {
+		dat, err = data.Fetch(data.Resolve(ts.uri, ts.ImageFile), -1)
+		if err != nil {
+			if ts.Data == "" {
+				return nil, err
+			}
+			dat = nil
+		}
+	}
+
+	if dat == nil {
+		if ts.Data == "" {
+			return nil, fmt.Errorf("tileset %q has no image", ts.uri)
+		}
+
+		dat, err = base64.StdEncoding.DecodeString(ts.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(dat))
+	return img, err
+}
+
 // subImager interface to return
 // an image representing the portion of
 // the tileset image visible through r.
@@ -0,0 +1,178 @@
+package tileset
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadImageFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	img.Set(3, 1, color.NRGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "img"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "img", "atlas.png"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	embedded := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	tests := []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{"image", "width: 4\nheight: 2\nimage: img/atlas.png\n", true},
+		{"fallback", "width: 4\nheight: 2\nimage: missing.png\ndata: " + embedded + "\n", true},
+		{"missing", "width: 4\nheight: 2\nimage: missing.png\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri := filepath.Join(dir, tt.name+".yml")
+			if err := ioutil.WriteFile(uri, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := Load(uri)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			atlas, err := res[0].Atlas()
+			if got := err == nil; got != tt.ok {
+				t.Fatalf("got [%v] want [%v] (%v)", got, tt.ok, err)
+			}
+			if !tt.ok {
+				return
+			}
+
+			if got, want := atlas.At(3, 1), (color.NRGBA{R: 255, A: 255}); color.NRGBAModel.Convert(got) != want {
+				t.Errorf("got [%v] want [%v]", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadTexturePacker(t *testing.T) {
+	res, err := Load("../testdata/spritesheet.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := res[0]
+
+	if ts.Width != 64 || ts.Height != 32 || ts.ImageFile != "spritesheet.png" {
+		t.Errorf("got [%d, %d, %s] want [64, 32, spritesheet.png]", ts.Width, ts.Height, ts.ImageFile)
+	}
+
+	tests := []struct {
+		id   string
+		rect image.Rectangle
+		want color.NRGBA
+	}{
+		{"red", image.Rect(0, 0, 32, 32), color.NRGBA{R: 0xd3, G: 0x2f, B: 0x2f, A: 255}},
+		{"blue", image.Rect(32, 0, 64, 32), color.NRGBA{R: 0x19, G: 0x76, B: 0xd2, A: 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			tile, ok := ts.Get(tt.id)
+			if !ok {
+				t.Fatalf("tile %q not found", tt.id)
+			}
+			if got := tile.Rect(); got != tt.rect {
+				t.Errorf("got [%v] want [%v]", got, tt.rect)
+			}
+
+			img, err := ts.Image(tile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := img.Bounds()
+			if got := color.NRGBAModel.Convert(img.At(b.Min.X, b.Min.Y)); got != tt.want {
+				t.Errorf("got [%v] want [%v]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalTexturePacker(t *testing.T) {
+	src := &Tileset{
+		Width: 64, Height: 32,
+		ImageFile: "atlas.png",
+		Tiles: []*Tile{
+			{ID: "a", MinX: 0, MinY: 0, MaxX: 32, MaxY: 32},
+			{ID: "b", MinX: 32, MinY: 0, MaxX: 64, MaxY: 32},
+		},
+	}
+
+	for _, array := range []bool{false, true} {
+		dat, err := src.MarshalTexturePacker(array)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := &Tileset{}
+		if err := unmarshalTexturePacker(dat, got); err != nil {
+			t.Fatal(err)
+		}
+
+		if got.ImageFile != src.ImageFile || got.Width != src.Width || len(got.Tiles) != len(src.Tiles) {
+			t.Fatalf("got [%v] want [%v]", got, src)
+		}
+		for i, el := range src.Tiles {
+			if *got.Tiles[i] != *el {
+				t.Errorf("got [%v] want [%v]", *got.Tiles[i], *el)
+			}
+		}
+	}
+
+	if _, err := (&Tileset{}).MarshalTexturePacker(false); err == nil {
+		t.Errorf("expected an error without image file")
+	}
+}
+
+func TestUnmarshalTexturePackerTrimmed(t *testing.T) {
+	tpl := `{"frames": [{"filename": "a.png", "frame": {"x": 0, "y": 0, "w": %s, "h": 32},
+		"rotated": false, "trimmed": %s, "spriteSourceSize": {"x": %s, "y": 0, "w": 30, "h": 32},
+		"sourceSize": {"w": 32, "h": 32}}], "meta": {"image": "atlas.png", "size": {"w": 32, "h": 32}}}`
+
+	tests := []struct {
+		w, trimmed, x string
+		ok            bool
+	}{
+		{"32", "false", "0", true},
+		// flagged as trimmed, but nothing was removed
+		{"32", "true", "0", true},
+		{"30", "true", "2", false},
+		{"30", "true", "0", false},
+	}
+
+	for _, tt := range tests {
+		src := fmt.Sprintf(tpl, tt.w, tt.trimmed, tt.x)
+		err := unmarshalTexturePacker([]byte(src), &Tileset{})
+		if got := err == nil; got != tt.ok {
+			t.Errorf("got [%v] want [%v] (%v)", got, tt.ok, err)
+		}
+	}
+}